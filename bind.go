@@ -0,0 +1,123 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// BindConfig defines options for decoding request bodies
+type BindConfig struct {
+	// DisallowUnknownFields rejects bodies with fields that don't exist in
+	// the target struct
+	DisallowUnknownFields bool
+
+	// MaxBytes is the largest body accepted. Default value is 1 MB.
+	MaxBytes int64
+}
+
+// Validator is implemented by types that check themselves after Bind decodes
+// them
+type Validator interface {
+	Validate() error
+}
+
+// BindError describes why a request body couldn't be bound. Status is the
+// response code that suits it: 400, 413 or 415.
+type BindError struct {
+	Status int
+	Err    error
+}
+
+// Error implements the error interface
+func (e *BindError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// Bind decodes a JSON request body of up to 1 MB into v, then calls
+// v.Validate if v implements Validator. Errors are *BindError values:
+//
+//	var input CreateUser
+//	if err := router.Bind(r, &input); err != nil {
+//		var bindErr *router.BindError
+//		errors.As(err, &bindErr)
+//		http.Error(w, err.Error(), bindErr.Status)
+//		return
+//	}
+func Bind(r *http.Request, v any) error {
+	return BindWithConfig(r, v, BindConfig{})
+}
+
+// BindWithConfig decodes a JSON request body into v with the provided
+// configuration. Requests with a Content-Type other than JSON are rejected,
+// as are bodies holding more than one JSON value.
+func BindWithConfig(r *http.Request, v any, config BindConfig) error {
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = 1 << 20
+	}
+
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json")) {
+			return &BindError{Status: http.StatusUnsupportedMediaType, Err: fmt.Errorf("router: unsupported Content-Type %q", contentType)}
+		}
+	}
+	if r.Body == nil || r.Body == http.NoBody {
+		return &BindError{Status: http.StatusBadRequest, Err: errors.New("router: request body is empty")}
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, config.MaxBytes))
+	if config.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+
+	if err := decoder.Decode(v); err != nil {
+		return bindDecodeError(err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return bindDecodeError(err)
+		}
+		return &BindError{Status: http.StatusBadRequest, Err: errors.New("router: request body must contain a single JSON value")}
+	}
+
+	if validator, ok := v.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			return &BindError{Status: http.StatusBadRequest, Err: err}
+		}
+	}
+	return nil
+}
+
+// bindDecodeError turns a decoding error into a BindError with a message that
+// is safe to send to the client
+func bindDecodeError(err error) error {
+	var maxErr *http.MaxBytesError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &maxErr):
+		return &BindError{Status: http.StatusRequestEntityTooLarge, Err: fmt.Errorf("router: request body must not be larger than %d bytes", maxErr.Limit)}
+	case errors.As(err, &syntaxErr):
+		return &BindError{Status: http.StatusBadRequest, Err: fmt.Errorf("router: malformed JSON at position %d", syntaxErr.Offset)}
+	case errors.As(err, &typeErr):
+		return &BindError{Status: http.StatusBadRequest, Err: fmt.Errorf("router: invalid value for field %q", typeErr.Field)}
+	case errors.Is(err, io.EOF):
+		return &BindError{Status: http.StatusBadRequest, Err: errors.New("router: request body is empty")}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return &BindError{Status: http.StatusBadRequest, Err: errors.New("router: malformed JSON")}
+	}
+	// Unknown fields and other decoder errors already read well
+	return &BindError{Status: http.StatusBadRequest, Err: fmt.Errorf("router: %w", err)}
+}
@@ -0,0 +1,50 @@
+package router
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// If returns a middleware that applies mw to the requests for which pred
+// returns true and passes the others straight to the next handler:
+//
+//	r.Use(router.If(router.IsMethod(http.MethodPost), middleware.BodyLimit(1<<20)))
+func If(pred func(*http.Request) bool, mw Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if pred(r) {
+				wrapped.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Unless returns a middleware that applies mw to every request except those
+// for which pred returns true:
+//
+//	r.Use(router.Unless(router.HasPathPrefix("/public"), Auth))
+func Unless(pred func(*http.Request) bool, mw Middleware) Middleware {
+	return If(func(r *http.Request) bool { return !pred(r) }, mw)
+}
+
+// HasPathPrefix returns a predicate matching requests whose path is prefix or
+// lies below it. The prefix matches whole segments, so "/public" matches
+// "/public/a.css" but not "/publications".
+func HasPathPrefix(prefix string) func(*http.Request) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return func(r *http.Request) bool {
+		rest, ok := strings.CutPrefix(r.URL.Path, prefix)
+		return ok && (rest == "" || strings.HasPrefix(rest, "/"))
+	}
+}
+
+// IsMethod returns a predicate matching requests with one of the given methods
+func IsMethod(methods ...string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		return slices.Contains(methods, r.Method)
+	}
+}
@@ -0,0 +1,118 @@
+package router
+
+import (
+	"errors"
+	"log"
+	"net/http"
+)
+
+// HandlerFuncE is a handler that returns an error instead of writing the error
+// response itself. Registered with HandleE or GetE and friends, a non-nil
+// error is passed to the router's error handler. It should be returned before
+// anything is written, as the error handler writes the whole response.
+type HandlerFuncE func(w http.ResponseWriter, r *http.Request) error
+
+// HTTPError is an error with the status code to respond with. Its message is
+// sent to the client, so it shouldn't carry internal details; Err is the
+// underlying cause, kept for logging and errors.Is.
+type HTTPError struct {
+	Status  int
+	Message string
+	Err     error
+}
+
+// NewHTTPError returns an HTTPError with the given status, using the status
+// text as the message if none is given
+func NewHTTPError(status int, message string) *HTTPError {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return &HTTPError{Status: status, Message: message}
+}
+
+// Error implements the error interface
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying error
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultErrorHandler is the error handler of routers without their own. An
+// *HTTPError is answered with its status and message and a *BindError with
+// its status and error text. Any other error is logged and answered with a
+// plain 500, so its details don't reach the client.
+func DefaultErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	var httpErr *HTTPError
+	var bindErr *BindError
+	switch {
+	case errors.As(err, &httpErr):
+		http.Error(w, httpErr.Message, httpErr.Status)
+	case errors.As(err, &bindErr):
+		http.Error(w, bindErr.Error(), bindErr.Status)
+	default:
+		log.Printf("router: %s %s: %v", r.Method, r.URL.Path, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// ErrorHandler sets the function that turns errors returned by HandlerFuncE
+// handlers into responses, replacing DefaultErrorHandler. Like middleware, it
+// applies to the routes registered after it is set, and groups inherit it.
+// It panics if HandleE routes are already registered on the router or its
+// groups, because they would silently keep the previous handler.
+//
+// Only returned errors reach it. A panicking handler still unwinds to a
+// Recoverer middleware, if any, as with ordinary handlers.
+func (r *Router) ErrorHandler(handler func(w http.ResponseWriter, r *http.Request, err error)) {
+	if r.handlesErrors {
+		panic("router: ErrorHandler must be called before HandleE routes are registered")
+	}
+	r.errorHandler = handler
+}
+
+// HandleE registers an error-returning handler for a specific method and path
+func (r *Router) HandleE(method, path string, handler HandlerFuncE) {
+	for router := r; router != nil; router = router.parent {
+		router.handlesErrors = true
+	}
+	onError := r.errorHandler
+	if onError == nil {
+		onError = DefaultErrorHandler
+	}
+	r.Handle(method, path, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := handler(w, req); err != nil {
+			onError(w, req, err)
+		}
+	}))
+}
+
+// GetE registers an error-returning GET handler for a specific path
+func (r *Router) GetE(path string, handler HandlerFuncE) {
+	r.HandleE(http.MethodGet, path, handler)
+}
+
+// PostE registers an error-returning POST handler for a specific path
+func (r *Router) PostE(path string, handler HandlerFuncE) {
+	r.HandleE(http.MethodPost, path, handler)
+}
+
+// PutE registers an error-returning PUT handler for a specific path
+func (r *Router) PutE(path string, handler HandlerFuncE) {
+	r.HandleE(http.MethodPut, path, handler)
+}
+
+// PatchE registers an error-returning PATCH handler for a specific path
+func (r *Router) PatchE(path string, handler HandlerFuncE) {
+	r.HandleE(http.MethodPatch, path, handler)
+}
+
+// DeleteE registers an error-returning DELETE handler for a specific path
+func (r *Router) DeleteE(path string, handler HandlerFuncE) {
+	r.HandleE(http.MethodDelete, path, handler)
+}
@@ -0,0 +1,61 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/jtclarkjr/router-go/routertest"
+)
+
+// teapotErrors answers every error with a 418
+func teapotErrors(w http.ResponseWriter, r *http.Request, err error) {
+	http.Error(w, err.Error(), http.StatusTeapot)
+}
+
+// failing returns err from every request
+func failing(err error) HandlerFuncE {
+	return func(http.ResponseWriter, *http.Request) error { return err }
+}
+
+func TestErrorHandler(t *testing.T) {
+	r := NewRouter()
+	r.ErrorHandler(teapotErrors)
+	r.GetE("/top", failing(errors.New("top")))
+	r.Route("/api", func(api *Router) {
+		api.GetE("/inherited", failing(errors.New("inherited")))
+	})
+
+	for _, target := range []string{"/top", "/api/inherited"} {
+		if rec := routertest.Get(r, target); rec.Code != http.StatusTeapot {
+			t.Errorf("GET %s: status = %d, want the error handler's 418", target, rec.Code)
+		}
+	}
+}
+
+func TestErrorHandlerAfterHandleEPanics(t *testing.T) {
+	register := map[string]func(r *Router){
+		"router": func(r *Router) { r.GetE("/", failing(nil)) },
+		"group": func(r *Router) {
+			r.Route("/api", func(api *Router) { api.GetE("/", failing(nil)) })
+		},
+		"With": func(r *Router) { r.With().GetE("/", failing(nil)) },
+	}
+	for name, fn := range register {
+		t.Run(name, func(t *testing.T) {
+			r := NewRouter()
+			fn(r)
+			defer func() {
+				if recover() == nil {
+					t.Error("ErrorHandler after HandleE routes didn't panic")
+				}
+			}()
+			r.ErrorHandler(teapotErrors)
+		})
+	}
+
+	// Ordinary routes don't hold on to the error handler
+	r := NewRouter()
+	r.Get("/", func(http.ResponseWriter, *http.Request) {})
+	r.ErrorHandler(teapotErrors)
+}
@@ -0,0 +1,211 @@
+package router
+
+import (
+	"net"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// hostTable holds the routing trees of the hosts registered with Host. It is
+// shared by a router and the copies returned by With and Name.
+type hostTable struct {
+	hosts []*hostRoutes
+}
+
+// hostRoutes is the routing tree for the requests to one host pattern
+type hostRoutes struct {
+	pattern string
+	labels  []hostLabel
+	tree    *node
+
+	// exact is set when the pattern has no wildcard or parameters
+	exact bool
+}
+
+// hostLabel is one dot-separated label of a host pattern
+type hostLabel struct {
+	// text is the literal label, "*" or the raw parameter template
+	text string
+
+	// param is the name of the parameter the label captures, if any
+	param string
+
+	// constraint is set for constrained parameters such as {id:int}
+	constraint *regexp.Regexp
+}
+
+// Host creates a subrouter for the requests whose Host header matches
+// pattern, such as "api.example.com". A leading "*" label matches one or
+// more labels, so "*.example.com" matches "a.example.com" and
+// "a.b.example.com" but not "example.com". A "{name}" label captures a single
+// label as a URL parameter, so "{tenant}.example.com" makes the subdomain
+// available as URLParam(r, "tenant"); constraints work as in paths but can't
+// contain a dot. Matching ignores case, the port and a trailing dot, and IP
+// addresses only match exact patterns.
+//
+// Exact hosts are tried before patterns with parameters, and those before
+// wildcards, longer patterns first. A request whose host matches a pattern is
+// routed with that host's routes only; other requests use the routes
+// registered on r directly. Like Route, the subrouter starts with the
+// middleware registered on r, and the router's NotFound, MethodNotAllowed and
+// other settings apply to its routes.
+//
+// Host must be called on a router created by NewRouter, not inside a Route or
+// Host group. Calling it again with the same pattern adds to the same routes.
+func (r *Router) Host(pattern string, fn func(router *Router)) {
+	if r.hosts == nil {
+		panic("router: Host must be called on the top-level router")
+	}
+
+	host := parseHost(strings.TrimSuffix(pattern, "."))
+	if i := slices.IndexFunc(r.hosts.hosts, func(existing *hostRoutes) bool {
+		return existing.pattern == host.pattern
+	}); i >= 0 {
+		host = r.hosts.hosts[i]
+	} else {
+		r.hosts.hosts = append(r.hosts.hosts, host)
+		slices.SortFunc(r.hosts.hosts, compareHosts)
+	}
+
+	subrouter := &Router{
+		tree:         host.tree,
+		middleware:   slices.Clone(r.middleware),
+		names:        r.names,
+		colonParams:  r.colonParams,
+		errorHandler: r.errorHandler,
+		parent:       r,
+	}
+	fn(subrouter)
+}
+
+// parseHost parses a host pattern, lowercasing its literal labels, and panics
+// if it is malformed
+func parseHost(pattern string) *hostRoutes {
+	host := &hostRoutes{tree: newNode(), exact: true}
+	seen := make(map[string]bool)
+	for i, text := range strings.Split(pattern, ".") {
+		params := parseParams(text)
+		if len(params) == 0 {
+			text = strings.ToLower(text)
+		}
+		label := hostLabel{text: text}
+		switch {
+		case text == "" || text == "*" && i > 0:
+			panic("router: invalid host pattern " + pattern)
+		case text == "*":
+			host.exact = false
+		case len(params) > 0:
+			param := params[0]
+			if len(params) > 1 || param.start != 0 || param.end != len(text) || param.catchAll || param.optional || !validParamName(param.name) || seen[param.name] {
+				panic("router: invalid host pattern " + pattern + ": a parameter must be a whole label like {name}")
+			}
+			seen[param.name] = true
+			label.param = param.name
+			if param.constraint != "" {
+				constraint, err := regexp.Compile("^(?:" + param.constraint + ")$")
+				if err != nil {
+					panic("router: invalid host pattern " + pattern + ": " + err.Error())
+				}
+				label.constraint = constraint
+			}
+			host.exact = false
+		case strings.ContainsAny(text, "{}"):
+			panic("router: invalid host pattern " + pattern + ": unbalanced braces")
+		}
+		host.labels = append(host.labels, label)
+	}
+
+	texts := make([]string, len(host.labels))
+	for i, label := range host.labels {
+		texts[i] = label.text
+	}
+	host.pattern = strings.Join(texts, ".")
+	return host
+}
+
+// compareHosts orders host patterns so exact hosts come first, then patterns
+// with parameters, then wildcards, longer patterns before shorter ones, with
+// ties broken by pattern text
+func compareHosts(a, b *hostRoutes) int {
+	if rank := a.rank() - b.rank(); rank != 0 {
+		return rank
+	}
+	if len(a.labels) != len(b.labels) {
+		return len(b.labels) - len(a.labels)
+	}
+	return strings.Compare(a.pattern, b.pattern)
+}
+
+// rank groups host patterns by how specific they are, lowest first
+func (h *hostRoutes) rank() int {
+	switch {
+	case h.exact:
+		return 0
+	case h.labels[0].text == "*":
+		return 2
+	default:
+		return 1
+	}
+}
+
+// treeFor returns the routing tree for a request's Host header, along with
+// the parameters captured from it
+func (r *Router) treeFor(host string) (*node, []paramValue) {
+	name := hostname(host)
+	_, err := netip.ParseAddr(name)
+	isIP := err == nil
+
+	labels := strings.Split(name, ".")
+	for _, candidate := range r.hosts.hosts {
+		if isIP && !candidate.exact {
+			continue
+		}
+		if params, ok := candidate.match(labels); ok {
+			return candidate.tree, params
+		}
+	}
+	return r.tree, nil
+}
+
+// match reports whether the labels of a request host match the pattern and
+// returns the parameters captured from them
+func (h *hostRoutes) match(labels []string) ([]paramValue, bool) {
+	patternLabels := h.labels
+	if h.labels[0].text == "*" {
+		patternLabels = h.labels[1:]
+		if len(labels) <= len(patternLabels) {
+			return nil, false
+		}
+		labels = labels[len(labels)-len(patternLabels):]
+	}
+	if len(labels) != len(patternLabels) {
+		return nil, false
+	}
+
+	var params []paramValue
+	for i, label := range patternLabels {
+		switch {
+		case label.param == "":
+			if labels[i] != label.text {
+				return nil, false
+			}
+		case label.constraint != nil && !label.constraint.MatchString(labels[i]):
+			return nil, false
+		default:
+			params = append(params, paramValue{key: label.param, value: labels[i]})
+		}
+	}
+	return params, true
+}
+
+// hostname lowercases a host and strips its port, the brackets of an IPv6
+// address and a trailing dot
+func hostname(host string) string {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
@@ -0,0 +1,44 @@
+// Package routectx holds request context values shared between the router and
+// its middleware packages, which cannot import each other.
+package routectx
+
+import "context"
+
+// key identifies values stored by this package
+type key int
+
+const (
+	patternKey key = iota
+	corsPolicyKey
+)
+
+// WithPattern returns a copy of ctx carrying the matched route pattern
+func WithPattern(ctx context.Context, pattern string) context.Context {
+	return context.WithValue(ctx, patternKey, pattern)
+}
+
+// Pattern returns the matched route pattern stored in ctx, or ""
+func Pattern(ctx context.Context) string {
+	if pattern, ok := ctx.Value(patternKey).(string); ok {
+		return pattern
+	}
+	return ""
+}
+
+// CORSHandler is implemented by the handlers of the CORS middleware. The
+// router records the policy of the innermost one wrapping each route when the
+// route is registered, so outer CORS middleware can leave requests to it.
+type CORSHandler interface {
+	CORSPolicy() any
+}
+
+// WithCORSPolicy returns a copy of ctx carrying the CORS policy of the matched
+// route
+func WithCORSPolicy(ctx context.Context, policy any) context.Context {
+	return context.WithValue(ctx, corsPolicyKey, policy)
+}
+
+// CORSPolicy returns the CORS policy of the matched route stored in ctx, or nil
+func CORSPolicy(ctx context.Context) any {
+	return ctx.Value(corsPolicyKey)
+}
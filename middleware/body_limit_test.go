@@ -0,0 +1,61 @@
+package middleware_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	router "github.com/jtclarkjr/router-go"
+	"github.com/jtclarkjr/router-go/middleware"
+	"github.com/jtclarkjr/router-go/routertest"
+)
+
+// readBody reads the whole request body, leaving errors to BodyLimit
+func readBody(w http.ResponseWriter, r *http.Request) {
+	io.Copy(io.Discard, r.Body)
+}
+
+func TestBodyLimit(t *testing.T) {
+	r := router.NewRouter()
+	r.Use(middleware.BodyLimit(10))
+	r.Post("/small", readBody)
+	r.With(middleware.BodyLimit(100)).Post("/upload", readBody)
+
+	tests := []struct {
+		target string
+		size   int
+		code   int
+	}{
+		{"/small", 10, http.StatusOK},
+		{"/small", 11, http.StatusRequestEntityTooLarge},
+		// The route's own limit replaces the global one
+		{"/upload", 50, http.StatusOK},
+		{"/upload", 101, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		rec := routertest.Request(r, http.MethodPost, tt.target, strings.NewReader(strings.Repeat("x", tt.size)))
+		if rec.Code != tt.code {
+			t.Errorf("POST %s with %d bytes: status = %d, want %d", tt.target, tt.size, rec.Code, tt.code)
+		}
+		if tt.code == http.StatusRequestEntityTooLarge && !strings.Contains(rec.Body.String(), "larger than") {
+			t.Errorf("POST %s with %d bytes: body = %q, want the limit explained", tt.target, tt.size, rec.Body.String())
+		}
+	}
+}
+
+func TestBodyLimitHandlerResponse(t *testing.T) {
+	// A handler that answers the read error itself keeps its response
+	r := router.NewRouter()
+	r.Use(middleware.BodyLimit(4))
+	r.Post("/", func(w http.ResponseWriter, req *http.Request) {
+		if _, err := io.ReadAll(req.Body); err != nil {
+			http.Error(w, "too big for us", http.StatusBadRequest)
+		}
+	})
+
+	rec := routertest.Request(r, http.MethodPost, "/", strings.NewReader("12345"))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "too big for us") {
+		t.Errorf("status = %d, body = %q, want the handler's 400", rec.Code, rec.Body.String())
+	}
+}
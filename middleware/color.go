@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// Color modes set by SetColorOutput
+const (
+	colorAuto int32 = iota
+	colorAlways
+	colorNever
+)
+
+// colorMode is the package-wide color setting shared by Logger, Recoverer and
+// EnvVarChecker
+var colorMode atomic.Int32
+
+// SetColorOutput turns ANSI colors on or off for every middleware that colors
+// its output: Logger, Recoverer and EnvVarChecker. By default they only color
+// output written to a terminal, and never when the NO_COLOR environment
+// variable is set, so logs sent to files, CI or the systemd journal stay
+// plain. It can be called at any time, including after the middleware is
+// created.
+func SetColorOutput(enabled bool) {
+	if enabled {
+		colorMode.Store(colorAlways)
+	} else {
+		colorMode.Store(colorNever)
+	}
+}
+
+// colorEnabled reports whether output to a writer should be colored.
+// terminal tells whether the writer is a terminal.
+func colorEnabled(terminal bool) bool {
+	switch colorMode.Load() {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	}
+	return terminal && os.Getenv("NO_COLOR") == ""
+}
+
+// ansi returns code if colors are enabled and "" otherwise
+func ansi(colors bool, code string) string {
+	if !colors {
+		return ""
+	}
+	return code
+}
+
+// isTerminal reports whether w writes to a terminal rather than a file or pipe
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
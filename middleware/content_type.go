@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// AllowContentType rejects requests whose body has a media type not in types
+// with a 415 Unsupported Media Type. Parameters such as "; charset=utf-8" are
+// ignored when comparing, and requests without a body pass through.
+func AllowContentType(types ...string) func(http.Handler) http.Handler {
+	allowed := make(map[string]struct{}, len(types))
+	for _, t := range types {
+		allowed[strings.ToLower(strings.TrimSpace(t))] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength == 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if _, ok := allowed[mediaType]; err != nil || !ok {
+				http.Error(w, "Unsupported Media Type", http.StatusUnsupportedMediaType)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jtclarkjr/router-go/routertest"
+)
+
+func TestAllowContentType(t *testing.T) {
+	h := AllowContentType("application/json", "Application/XML")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		code        int
+	}{
+		{"exact", "application/json", "{}", http.StatusOK},
+		{"charset", "application/json; charset=utf-8", "{}", http.StatusOK},
+		{"case and spacing", "Application/JSON ;charset=UTF-8", "{}", http.StatusOK},
+		{"configured in mixed case", "application/xml", "<a/>", http.StatusOK},
+		{"other type", "text/plain", "hi", http.StatusUnsupportedMediaType},
+		{"missing", "", "{}", http.StatusUnsupportedMediaType},
+		{"malformed", "application/json;;", "{}", http.StatusUnsupportedMediaType},
+		{"no body", "text/plain", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.body == "" {
+				req.Body = http.NoBody
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			if rec := routertest.Do(h, req); rec.Code != tt.code {
+				t.Errorf("status = %d, want %d", rec.Code, tt.code)
+			}
+		})
+	}
+
+	if rec := routertest.JSON(h, http.MethodPut, "/", map[string]int{"a": 1}); rec.Code != http.StatusOK {
+		t.Errorf("routertest.JSON request: status = %d, want 200", rec.Code)
+	}
+}
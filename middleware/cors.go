@@ -1,12 +1,44 @@
 package middleware
 
 import (
+	"log/slog"
 	"net/http"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
+
+	"github.com/jtclarkjr/router-go/internal/routectx"
 )
 
+// corsKey marks requests an outer CORS middleware has already handled
+var corsKey = NewKey[bool]("cors")
+
+// corsHandler is the handler CORS wraps the next handler with. Through
+// CORSPolicy, the router records the innermost one of each route when it
+// registers the route.
+type corsHandler struct {
+	http.HandlerFunc
+	policy *CORSConfig
+}
+
+// CORSPolicy identifies the CORS middleware that created the handler
+func (h corsHandler) CORSPolicy() any {
+	return h.policy
+}
+
+// corsHeaders are the response headers a CORS middleware may set
+var corsHeaders = []string{
+	"Access-Control-Allow-Origin",
+	"Access-Control-Allow-Credentials",
+	"Access-Control-Allow-Methods",
+	"Access-Control-Allow-Headers",
+	"Access-Control-Max-Age",
+	"Access-Control-Expose-Headers",
+	"Access-Control-Allow-Private-Network",
+	"X-CORS-Debug",
+}
+
 // CORSConfig defines the configuration for CORS middleware
 type CORSConfig struct {
 	// AllowedOrigins is a list of origins a cross-domain request can be executed from.
@@ -17,6 +49,17 @@ type CORSConfig struct {
 	// Default value is ["*"]
 	AllowedOrigins []string
 
+	// AllowedOriginPatterns are regular expressions matched against the
+	// origin, for rules a single wildcard can't express. Anchor them with ^ and
+	// $, e.g. ^https://(dev|staging)\.example\.com$. They are only tried when
+	// AllowedOrigins doesn't match.
+	AllowedOriginPatterns []*regexp.Regexp
+
+	// AllowOriginFunc decides dynamically whether an origin is allowed, e.g.
+	// from a database of tenants. It is consulted when neither AllowedOrigins
+	// nor AllowedOriginPatterns match, and allowed origins are echoed back.
+	AllowOriginFunc func(r *http.Request, origin string) bool
+
 	// AllowedMethods is a list of methods the client is allowed to use with
 	// cross-domain requests. Default value is simple methods (HEAD, GET and POST).
 	AllowedMethods []string
@@ -39,12 +82,26 @@ type CORSConfig struct {
 	// cookies, HTTP authentication or client side SSL certificates.
 	AllowCredentials bool
 
+	// AllowPrivateNetwork answers Private Network Access preflights, sent by
+	// browsers when a public site calls an endpoint on a private network, with
+	// Access-Control-Allow-Private-Network: true
+	AllowPrivateNetwork bool
+
 	// OptionsPassthrough instructs preflight to let other potential next handlers to
 	// process the OPTIONS method. Turn this on if your application handles OPTIONS.
 	OptionsPassthrough bool
 
-	// Debugging turns on debug logging
+	// Debug logs why origins are rejected and how preflights are answered,
+	// so CORS failures can be diagnosed server-side
 	Debug bool
+
+	// Logger receives the Debug output. Default is slog.Default().
+	Logger *slog.Logger
+
+	// DebugHeaders also reports the decisions to the client in an
+	// X-CORS-Debug response header. It is meant for development only, as it
+	// tells anyone which origins are rejected.
+	DebugHeaders bool
 }
 
 // DefaultCORSConfig returns a generic default configuration with "*" for allowed origins
@@ -67,10 +124,19 @@ func DefaultCORSConfig() CORSConfig {
 	}
 }
 
-// CORS creates a new CORS middleware with the provided configuration
+// CORS creates a new CORS middleware with the provided configuration.
+//
+// CORS middleware can be stacked, e.g. globally with Use and again on a group
+// or with With, and the innermost one wins. The router records the innermost
+// CORS of each route when it is registered, and outer ones pass the route's
+// requests, preflights included, straight on to it. Stacked outside a router,
+// the inner one removes the CORS headers set by outer ones before applying its
+// own. Preflights for paths without an OPTIONS route are answered by the
+// router's stack, so to give a route its own preflight policy, register
+// OPTIONS for it alongside its other methods.
 func CORS(config CORSConfig) func(http.Handler) http.Handler {
 	// Set defaults if not provided
-	if len(config.AllowedOrigins) == 0 {
+	if len(config.AllowedOrigins) == 0 && len(config.AllowedOriginPatterns) == 0 && config.AllowOriginFunc == nil {
 		config.AllowedOrigins = []string{"*"}
 	}
 	if len(config.AllowedMethods) == 0 {
@@ -96,13 +162,49 @@ func CORS(config CORSConfig) func(http.Handler) http.Handler {
 
 	allowAllHeaders := slices.Contains(config.AllowedHeaders, "*")
 
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	policy := &config
 	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		return corsHandler{policy: policy, HandlerFunc: func(w http.ResponseWriter, r *http.Request) {
+			// A CORS further in on the matched route takes over
+			if inner, ok := routectx.CORSPolicy(r.Context()).(*CORSConfig); ok && inner != policy {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			origin := r.Header.Get("Origin")
 
+			// Without an Origin this isn't a CORS request, e.g. a same-origin
+			// GET or a request from curl, so it is passed through untouched.
+			// An OPTIONS request is then left to the router like any other.
+			if origin == "" {
+				if !allowAllOrigins || config.AllowCredentials {
+					// The response differs for requests with an Origin, so
+					// caches must not serve this one to them
+					addVaryOrigin(w.Header())
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if _, nested := Value(r, corsKey); nested {
+				// An inner CORS replaces whatever an outer one decided
+				for _, name := range corsHeaders {
+					w.Header().Del(name)
+				}
+			} else {
+				r = WithValue(r, corsKey, true)
+			}
+
 			// Check if origin is allowed
-			if !isOriginAllowed(origin, config.AllowedOrigins, wildcardOrigins, allowAllOrigins) {
+			if !isOriginAllowed(r, origin, config, wildcardOrigins, allowAllOrigins) {
 				if config.Debug {
+					config.Logger.Info("CORS origin not allowed", "origin", origin, "method", r.Method, "path", r.URL.Path)
+				}
+				if config.DebugHeaders {
 					w.Header().Set("X-CORS-Debug", "Origin not allowed: "+origin)
 				}
 				// If origin is not allowed and this is a preflight, reject it
@@ -120,7 +222,7 @@ func CORS(config CORSConfig) func(http.Handler) http.Handler {
 				w.Header().Set("Access-Control-Allow-Origin", "*")
 			} else {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Add("Vary", "Origin")
+				addVaryOrigin(w.Header())
 			}
 
 			// Set credentials header
@@ -135,16 +237,27 @@ func CORS(config CORSConfig) func(http.Handler) http.Handler {
 					w.Header().Set("Access-Control-Allow-Methods", strings.Join(config.AllowedMethods, ", "))
 				}
 
-				// Set allowed headers
+				// Set allowed headers. With allow-all, the requested headers are
+				// echoed rather than sending "*", which browsers ignore for
+				// credentialed requests and which never covers Authorization.
 				requestedHeaders := r.Header.Get("Access-Control-Request-Headers")
-				if allowAllHeaders || requestedHeaders == "" {
-					w.Header().Set("Access-Control-Allow-Headers", requestedHeaders)
-				} else if len(config.AllowedHeaders) > 0 {
-					// Check if requested headers are in the allowed list
-					allowed := filterAllowedHeaders(requestedHeaders, config.AllowedHeaders)
+				if requestedHeaders != "" {
+					var allowed string
+					if allowAllHeaders {
+						allowed = normalizeHeaderList(requestedHeaders)
+					} else {
+						allowed = filterAllowedHeaders(requestedHeaders, config.AllowedHeaders)
+					}
 					if allowed != "" {
 						w.Header().Set("Access-Control-Allow-Headers", allowed)
 					}
+					if allowAllHeaders {
+						w.Header().Add("Vary", "Access-Control-Request-Headers")
+					}
+				}
+
+				if config.AllowPrivateNetwork && r.Header.Get("Access-Control-Request-Private-Network") == "true" {
+					w.Header().Set("Access-Control-Allow-Private-Network", "true")
 				}
 
 				// Set max age
@@ -153,6 +266,12 @@ func CORS(config CORSConfig) func(http.Handler) http.Handler {
 				}
 
 				if config.Debug {
+					config.Logger.Info("CORS preflight answered", "origin", origin, "path", r.URL.Path,
+						"request_method", r.Header.Get("Access-Control-Request-Method"),
+						"request_headers", requestedHeaders,
+						"allowed_headers", w.Header().Get("Access-Control-Allow-Headers"))
+				}
+				if config.DebugHeaders {
 					w.Header().Set("X-CORS-Debug", "Preflight response")
 				}
 
@@ -169,7 +288,14 @@ func CORS(config CORSConfig) func(http.Handler) http.Handler {
 			}
 
 			next.ServeHTTP(w, r)
-		})
+		}}
+	}
+}
+
+// addVaryOrigin adds Origin to the Vary header unless it is already listed
+func addVaryOrigin(header http.Header) {
+	if !slices.Contains(header.Values("Vary"), "Origin") {
+		header.Add("Vary", "Origin")
 	}
 }
 
@@ -201,17 +327,13 @@ func (w wildcardOrigin) match(origin string) bool {
 }
 
 // isOriginAllowed checks if the origin is in the allowed list
-func isOriginAllowed(origin string, allowedOrigins []string, wildcardOrigins []wildcardOrigin, allowAll bool) bool {
+func isOriginAllowed(r *http.Request, origin string, config CORSConfig, wildcardOrigins []wildcardOrigin, allowAll bool) bool {
 	if allowAll {
 		return true
 	}
 
-	if origin == "" {
-		return false
-	}
-
 	// Check exact matches
-	if slices.Contains(allowedOrigins, origin) {
+	if slices.Contains(config.AllowedOrigins, origin) {
 		return true
 	}
 
@@ -222,7 +344,26 @@ func isOriginAllowed(origin string, allowedOrigins []string, wildcardOrigins []w
 		}
 	}
 
-	return false
+	// Fall back to the regular expressions
+	for _, pattern := range config.AllowedOriginPatterns {
+		if pattern.MatchString(origin) {
+			return true
+		}
+	}
+
+	return config.AllowOriginFunc != nil && config.AllowOriginFunc(r, origin)
+}
+
+// normalizeHeaderList lowercases and trims a comma-separated header list,
+// dropping empty entries
+func normalizeHeaderList(list string) string {
+	var headers []string
+	for _, h := range strings.Split(list, ",") {
+		if h = strings.TrimSpace(strings.ToLower(h)); h != "" {
+			headers = append(headers, h)
+		}
+	}
+	return strings.Join(headers, ", ")
 }
 
 // filterAllowedHeaders filters the requested headers against the allowed headers
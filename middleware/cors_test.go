@@ -0,0 +1,232 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	router "github.com/jtclarkjr/router-go"
+	"github.com/jtclarkjr/router-go/middleware"
+	"github.com/jtclarkjr/router-go/routertest"
+)
+
+// corsRequest builds a request from origin, as a preflight for POST when
+// method is OPTIONS
+func corsRequest(method, target, origin string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("Origin", origin)
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	}
+	return req
+}
+
+// partnerCORS only allows https://partner.example.com
+func partnerCORS() func(http.Handler) http.Handler {
+	return middleware.CORS(middleware.CORSConfig{
+		AllowedOrigins: []string{"https://partner.example.com"},
+		AllowedMethods: []string{http.MethodPost},
+	})
+}
+
+func TestCORSPerRoute(t *testing.T) {
+	calls := 0
+	r := router.NewRouter()
+	r.Use(middleware.SimpleCORS())
+	r.With(partnerCORS()).Methods([]string{http.MethodPost, http.MethodOptions}, "/webhooks", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+	})
+	r.Get("/public", func(w http.ResponseWriter, req *http.Request) {})
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		origin string
+		code   int
+		allow  []string
+	}{
+		{"route preflight", http.MethodOptions, "/webhooks", "https://partner.example.com", http.StatusNoContent, []string{"https://partner.example.com"}},
+		{"route preflight rejected", http.MethodOptions, "/webhooks", "https://other.example.com", http.StatusForbidden, nil},
+		{"route request", http.MethodPost, "/webhooks", "https://partner.example.com", http.StatusOK, []string{"https://partner.example.com"}},
+		{"route request rejected", http.MethodPost, "/webhooks", "https://other.example.com", http.StatusOK, nil},
+		{"global request", http.MethodGet, "/public", "https://other.example.com", http.StatusOK, []string{"*"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := routertest.Do(r, corsRequest(tt.method, tt.target, tt.origin))
+			if rec.Code != tt.code {
+				t.Errorf("status = %d, want %d", rec.Code, tt.code)
+			}
+			if got := rec.Header().Values("Access-Control-Allow-Origin"); !slices.Equal(got, tt.allow) {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.allow)
+			}
+		})
+	}
+
+	// Only the two actual POSTs reach the handler, never a preflight
+	if calls != 2 {
+		t.Errorf("handler called %d times, want 2", calls)
+	}
+}
+
+func TestCORSGroup(t *testing.T) {
+	r := router.NewRouter()
+	r.Use(middleware.SimpleCORS())
+	r.Route("/admin", func(admin *router.Router) {
+		admin.Use(partnerCORS())
+		admin.Post("/users", func(w http.ResponseWriter, req *http.Request) {})
+	})
+
+	rec := routertest.Do(r, corsRequest(http.MethodPost, "/admin/users", "https://other.example.com"))
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q for a disallowed origin, want none", got)
+	}
+	rec = routertest.Do(r, corsRequest(http.MethodPost, "/admin/users", "https://partner.example.com"))
+	if got := rec.Header().Values("Access-Control-Allow-Origin"); !slices.Equal(got, []string{"https://partner.example.com"}) {
+		t.Errorf("Access-Control-Allow-Origin = %q, want the partner origin once", got)
+	}
+}
+
+func TestCORSPreflightSkipsHandler(t *testing.T) {
+	calls := 0
+	r := router.NewRouter()
+	r.Use(middleware.SimpleCORS())
+	r.Options("/items", func(w http.ResponseWriter, req *http.Request) {
+		calls++
+	})
+
+	rec := routertest.Do(r, corsRequest(http.MethodOptions, "/items", "https://app.example.com"))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want 204", rec.Code)
+	}
+	if calls != 0 {
+		t.Errorf("OPTIONS handler ran %d times during a preflight", calls)
+	}
+}
+
+func TestCORSStackedWithoutRouter(t *testing.T) {
+	h := middleware.SimpleCORS()(partnerCORS()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	rec := routertest.Do(h, corsRequest(http.MethodPost, "/", "https://partner.example.com"))
+	if got := rec.Header().Values("Access-Control-Allow-Origin"); !slices.Equal(got, []string{"https://partner.example.com"}) {
+		t.Errorf("Access-Control-Allow-Origin = %q, want the inner policy's origin once", got)
+	}
+}
+
+func TestCORSPreflightAllowHeaders(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	credentialed := middleware.CORS(middleware.CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{http.MethodPost},
+		AllowedHeaders:   []string{"*"},
+		AllowCredentials: true,
+	})(handler)
+	listed := middleware.CORS(middleware.CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedHeaders: []string{"Content-Type"},
+	})(handler)
+
+	tests := []struct {
+		name      string
+		handler   http.Handler
+		requested string
+		want      []string
+	}{
+		{"credentialed wildcard echoes", credentialed, "X-Custom, Authorization", []string{"x-custom, authorization"}},
+		{"credentialed wildcard without request", credentialed, "", nil},
+		{"listed filters", listed, "Content-Type, X-Other", []string{"content-type"}},
+		{"listed without match", listed, "X-Other", nil},
+		{"listed without request", listed, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := corsRequest(http.MethodOptions, "/", "https://app.example.com")
+			if tt.requested != "" {
+				req.Header.Set("Access-Control-Request-Headers", tt.requested)
+			}
+			rec := routertest.Do(tt.handler, req)
+			if rec.Code != http.StatusNoContent {
+				t.Errorf("status = %d, want 204", rec.Code)
+			}
+			if got := rec.Header().Values("Access-Control-Allow-Headers"); !slices.Equal(got, tt.want) {
+				t.Errorf("Access-Control-Allow-Headers = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	req := corsRequest(http.MethodOptions, "/", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Headers", "X-Custom")
+	rec := routertest.Do(credentialed, req)
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want true", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want the origin rather than *", got)
+	}
+	if vary := rec.Header().Values("Vary"); !slices.Contains(vary, "Access-Control-Request-Headers") {
+		t.Errorf("Vary = %q, want Access-Control-Request-Headers listed", vary)
+	}
+}
+
+func TestCORSWithoutOrigin(t *testing.T) {
+	r := router.NewRouter()
+	r.Use(middleware.CORS(middleware.CORSConfig{AllowedOrigins: []string{"https://app.example.com"}}))
+	r.Options("/items", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("options handler"))
+	})
+	r.Get("/items", func(w http.ResponseWriter, req *http.Request) {})
+
+	// Without an Origin, OPTIONS isn't a preflight and reaches the route
+	rec := routertest.Request(r, http.MethodOptions, "/items", nil)
+	if rec.Code != http.StatusOK || rec.Body.String() != "options handler" {
+		t.Errorf("OPTIONS without Origin: status = %d, body = %q, want the route's response", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("OPTIONS without Origin: Access-Control-Allow-Origin = %q, want none", got)
+	}
+	if vary := rec.Header().Values("Vary"); !slices.Contains(vary, "Origin") {
+		t.Errorf("OPTIONS without Origin: Vary = %q, want Origin", vary)
+	}
+
+	// With a disallowed Origin, it is a rejected preflight
+	rec = routertest.Do(r, corsRequest(http.MethodOptions, "/items", "https://evil.example.com"))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("OPTIONS from a disallowed origin: status = %d, want 403", rec.Code)
+	}
+
+	// With an allowed Origin, the preflight is answered
+	rec = routertest.Do(r, corsRequest(http.MethodOptions, "/items", "https://app.example.com"))
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Errorf("OPTIONS from an allowed origin: status = %d, Access-Control-Allow-Origin = %q", rec.Code, rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	// A plain GET without Origin passes through untouched
+	if rec := routertest.Get(r, "/items"); rec.Code != http.StatusOK {
+		t.Errorf("GET without Origin: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestCORSPreflightWithoutOptionsRoute(t *testing.T) {
+	r := router.NewRouter()
+	r.Use(middleware.CORS(middleware.DefaultCORSConfig()))
+	r.Get("/x", func(w http.ResponseWriter, req *http.Request) {})
+
+	// AutoOptions is off, so these would otherwise be a 405 and a 404
+	for _, target := range []string{"/x", "/y"} {
+		req := httptest.NewRequest(http.MethodOptions, target, nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		rec := routertest.Do(r, req)
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("OPTIONS %s: status = %d, want 204", target, rec.Code)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+			t.Errorf("OPTIONS %s: Access-Control-Allow-Origin = %q, want *", target, got)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Methods"); got == "" {
+			t.Errorf("OPTIONS %s: no Access-Control-Allow-Methods", target)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jtclarkjr/router-go/routertest"
+)
+
+// csrfServer answers with the token CSRF passed on
+var csrfServer = CSRF(CSRFConfig{HeaderName: "X-Token", CookieName: "tok", SameSite: http.SameSiteStrictMode})(
+	http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(CSRFToken(r)))
+	}),
+)
+
+// csrfCookie fetches a page and returns the token cookie it sets
+func csrfCookie(t *testing.T) *http.Cookie {
+	t.Helper()
+	rec := routertest.Get(csrfServer, "/form")
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "tok" {
+		t.Fatalf("cookies = %v, want one named tok", cookies)
+	}
+	if cookies[0].Value != rec.Body.String() {
+		t.Fatalf("CSRFToken = %q, cookie = %q", rec.Body.String(), cookies[0].Value)
+	}
+	if cookies[0].SameSite != http.SameSiteStrictMode || !cookies[0].HttpOnly {
+		t.Errorf("cookie attributes = %+v, want HttpOnly and SameSite=Strict", cookies[0])
+	}
+	return cookies[0]
+}
+
+func TestCSRFValidToken(t *testing.T) {
+	cookie := csrfCookie(t)
+
+	// In the configured header
+	req := httptest.NewRequest(http.MethodPost, "/form", nil)
+	req.AddCookie(cookie)
+	req.Header.Set("X-Token", cookie.Value)
+	if rec := routertest.Do(csrfServer, req); rec.Code != http.StatusOK {
+		t.Errorf("header token: status = %d, want 200", rec.Code)
+	}
+
+	// In the form field
+	form := url.Values{"csrf_token": {cookie.Value}}
+	req = httptest.NewRequest(http.MethodPost, "/form", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+	if rec := routertest.Do(csrfServer, req); rec.Code != http.StatusOK {
+		t.Errorf("form token: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestCSRFForgedToken(t *testing.T) {
+	cookie := csrfCookie(t)
+	other := csrfCookie(t)
+
+	tests := []struct {
+		name   string
+		method string
+		cookie *http.Cookie
+		token  string
+	}{
+		{"missing token", http.MethodPost, cookie, ""},
+		{"wrong token", http.MethodPut, cookie, other.Value},
+		{"no cookie", http.MethodDelete, nil, cookie.Value},
+		{"forged cookie", http.MethodPatch, &http.Cookie{Name: "tok", Value: "forged"}, "forged"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/form", nil)
+			if tt.cookie != nil {
+				req.AddCookie(tt.cookie)
+			}
+			if tt.token != "" {
+				req.Header.Set("X-Token", tt.token)
+			}
+			if rec := routertest.Do(csrfServer, req); rec.Code != http.StatusForbidden {
+				t.Errorf("status = %d, want 403", rec.Code)
+			}
+		})
+	}
+}
+
+func TestCSRFSafeMethods(t *testing.T) {
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace} {
+		if rec := routertest.Request(csrfServer, method, "/form", nil); rec.Code != http.StatusOK {
+			t.Errorf("%s without a token: status = %d, want 200", method, rec.Code)
+		}
+	}
+}
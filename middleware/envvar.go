@@ -1,7 +1,8 @@
 package middleware
 
 import (
-	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -9,32 +10,22 @@ import (
 
 // EnvVarChecker returns a middleware that checks if the given environment variables are not empty.
 // If any are empty, it responds with 500 and a message listing the missing variables.
+// Environment variables rarely change while a server runs, so prefer CheckEnvVars at startup.
 func EnvVarChecker(envVars ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			missing := []string{}
-			for _, v := range envVars {
-				if os.Getenv(v) == "" {
-					missing = append(missing, v)
-				}
-			}
-			if len(missing) > 0 {
+			if missing := missingEnvVars(envVars); len(missing) > 0 {
 				errMsg := "Missing required environment variables: [" + joinStrings(missing, ", ") + "]"
 				// Log the error so it appears in the package user's logs
-				errorColor := "\033[31m" // Red
-				resetColor := "\033[0m"
-				log.Printf("%s[EnvVarChecker] %s%s", errorColor, errMsg, resetColor)
-				// Add error to request context for upstream middleware/handlers
-				type ctxKey string
-				ctx := context.WithValue(r.Context(), ctxKey("envvar_error"), errMsg)
-				// Pass the new context to the logger and any downstream middleware
-				r2 := r.WithContext(ctx)
+				colors := colorEnabled(isTerminal(log.Writer()))
+				log.Printf("%s[EnvVarChecker] %s%s", ansi(colors, Red), errMsg, ansi(colors, Reset))
+				// The response is complete, so next isn't called. Logger still
+				// records the 500 and reports the error attached here.
+				WithError(r.Context(), errors.New(errMsg))
 				w.WriteHeader(http.StatusInternalServerError)
 				if _, err := w.Write([]byte(errMsg)); err != nil {
 					log.Printf("Failed to write error response: %v", err)
 				}
-				// Call next with the new context in case logger or others want to log error
-				next.ServeHTTP(w, r2)
 				return
 			}
 			next.ServeHTTP(w, r)
@@ -42,6 +33,26 @@ func EnvVarChecker(envVars ...string) func(http.Handler) http.Handler {
 	}
 }
 
+// CheckEnvVars reports an error listing every given environment variable that
+// is empty. Call it once at startup so a misconfigured deploy fails fast.
+func CheckEnvVars(envVars ...string) error {
+	if missing := missingEnvVars(envVars); len(missing) > 0 {
+		return fmt.Errorf("missing required environment variables: [%s]", joinStrings(missing, ", "))
+	}
+	return nil
+}
+
+// missingEnvVars returns the environment variables that are empty
+func missingEnvVars(envVars []string) []string {
+	missing := []string{}
+	for _, v := range envVars {
+		if os.Getenv(v) == "" {
+			missing = append(missing, v)
+		}
+	}
+	return missing
+}
+
 // joinStrings joins a slice of strings with the given separator.
 func joinStrings(strs []string, sep string) string {
 	if len(strs) == 0 {
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// errorKey stores the slot for the request error
+var errorKey = NewKey[*errorSlot]("error")
+
+// errorSlot holds the error attached to a request. Loggers install it before
+// calling the next handler, so errors attached further in are visible to them.
+type errorSlot struct {
+	mu  sync.Mutex
+	err error
+}
+
+// WithError attaches err to the request for Logger and StructuredLogger to
+// report. The error is visible to middleware wrapping the caller, so handlers
+// can ignore the returned context; it is returned so handlers further in can
+// read the error too.
+func WithError(ctx context.Context, err error) context.Context {
+	if slot, ok := ctx.Value(errorKey).(*errorSlot); ok {
+		slot.mu.Lock()
+		slot.err = err
+		slot.mu.Unlock()
+		return ctx
+	}
+	return context.WithValue(ctx, errorKey, &errorSlot{err: err})
+}
+
+// ErrorFromContext returns the error attached to the request with WithError,
+// or nil
+func ErrorFromContext(r *http.Request) error {
+	slot, ok := Value(r, errorKey)
+	if !ok {
+		return nil
+	}
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+	return slot.err
+}
+
+// withErrorSlot makes sure the request carries an error slot, so errors
+// attached by the next handlers can be read back afterwards
+func withErrorSlot(r *http.Request) *http.Request {
+	if _, ok := Value(r, errorKey); ok {
+		return r
+	}
+	return WithValue(r, errorKey, &errorSlot{})
+}
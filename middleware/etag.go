@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// ETagConfig defines the configuration for the ETag middleware
+type ETagConfig struct {
+	// Weak produces weak validators (W/"..."), for responses that are
+	// semantically but not byte-for-byte equivalent, e.g. when compressed
+	Weak bool
+
+	// MaxBufferSize is the largest body that is buffered and hashed. Larger
+	// responses are streamed without an ETag. Default value is 1 MB.
+	MaxBufferSize int
+}
+
+// ETag is a middleware that sets a strong ETag on GET and HEAD responses and
+// answers matching If-None-Match requests with 304 Not Modified
+func ETag(next http.Handler) http.Handler {
+	return ETagWithConfig(ETagConfig{})(next)
+}
+
+// ETagWithConfig creates an ETag middleware with custom configuration. The
+// response is buffered to hash its body, so only 200 responses up to
+// MaxBufferSize get a tag. Responses that already carry an ETag, are marked
+// no-store, or are flushed by the handler are passed through unchanged, as
+// are WebSocket upgrades.
+func ETagWithConfig(config ETagConfig) func(http.Handler) http.Handler {
+	if config.MaxBufferSize <= 0 {
+		config.MaxBufferSize = 1 << 20
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead || IsWebSocketUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ew := &etagWriter{ResponseWriter: w, statusCode: http.StatusOK, maxSize: config.MaxBufferSize}
+			next.ServeHTTP(ew, r)
+			if ew.streaming {
+				return
+			}
+
+			header := w.Header()
+			if ew.statusCode != http.StatusOK || header.Get("ETag") != "" ||
+				strings.Contains(header.Get("Cache-Control"), "no-store") {
+				ew.flushBuffer()
+				return
+			}
+
+			sum := sha256.Sum256(ew.buf.Bytes())
+			tag := `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+			if config.Weak {
+				tag = "W/" + tag
+			}
+			header.Set("ETag", tag)
+
+			if etagMatches(r.Header.Get("If-None-Match"), tag) {
+				header.Del("Content-Type")
+				header.Del("Content-Length")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			ew.flushBuffer()
+		})
+	}
+}
+
+// etagMatches reports whether an If-None-Match value matches tag, using the
+// weak comparison RFC 9110 requires for If-None-Match
+func etagMatches(ifNoneMatch, tag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// etagWriter buffers a response until it is complete, switching to streaming
+// once the body outgrows the buffer or the handler flushes
+type etagWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	buf         bytes.Buffer
+	maxSize     int
+	streaming   bool
+}
+
+// WriteHeader records the status code until the response is complete
+func (ew *etagWriter) WriteHeader(code int) {
+	if ew.streaming {
+		ew.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if ew.wroteHeader {
+		return
+	}
+	ew.statusCode = code
+	ew.wroteHeader = true
+}
+
+// Write buffers the body, or passes it through once streaming
+func (ew *etagWriter) Write(b []byte) (int, error) {
+	if !ew.streaming && ew.buf.Len()+len(b) > ew.maxSize {
+		ew.startStreaming()
+	}
+	if ew.streaming {
+		return ew.ResponseWriter.Write(b)
+	}
+	ew.wroteHeader = true
+	return ew.buf.Write(b)
+}
+
+// Flush gives up on the ETag and streams the response
+func (ew *etagWriter) Flush() {
+	if !ew.streaming {
+		ew.startStreaming()
+	}
+	if f, ok := ew.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (ew *etagWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
+
+// startStreaming writes out what has been buffered and stops buffering
+func (ew *etagWriter) startStreaming() {
+	ew.flushBuffer()
+	ew.streaming = true
+}
+
+// flushBuffer writes the buffered status and body to the client
+func (ew *etagWriter) flushBuffer() {
+	ew.ResponseWriter.WriteHeader(ew.statusCode)
+	if ew.buf.Len() > 0 {
+		_, _ = ew.ResponseWriter.Write(ew.buf.Bytes())
+		ew.buf.Reset()
+	}
+}
@@ -0,0 +1,321 @@
+// Package jwt provides a middleware that verifies JSON Web Tokens. It lives in
+// its own package so applications that don't need it don't link its crypto.
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	// Register the hash functions used by the supported algorithms
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// Errors passed to the ErrorHandler
+var (
+	ErrMissingToken     = errors.New("jwt: missing token")
+	ErrMalformedToken   = errors.New("jwt: malformed token")
+	ErrUnsupportedAlg   = errors.New("jwt: unsupported signing algorithm")
+	ErrInvalidSignature = errors.New("jwt: invalid signature")
+	ErrTokenExpired     = errors.New("jwt: token is expired")
+	ErrTokenNotYetValid = errors.New("jwt: token is not valid yet")
+	ErrInvalidIssuer    = errors.New("jwt: invalid issuer")
+	ErrInvalidAudience  = errors.New("jwt: invalid audience")
+)
+
+// claimsKey is the context key type for the verified claims
+type claimsKey struct{}
+
+// Claims holds the payload of a verified token
+type Claims map[string]any
+
+// Subject returns the "sub" claim, or ""
+func (c Claims) Subject() string {
+	s, _ := c["sub"].(string)
+	return s
+}
+
+// JWTConfig defines the configuration for the JWT middleware
+type JWTConfig struct {
+	// Keys provides the verification key for a token. Use HMACKey, PublicKey
+	// or JWKS. Required.
+	Keys KeySource
+
+	// Cookie is read for the token when the request has no bearer token in the
+	// Authorization header. Default value is "" (cookies are not read).
+	Cookie string
+
+	// Issuer, when set, must match the "iss" claim
+	Issuer string
+
+	// Audience, when set, must be listed in the "aud" claim
+	Audience string
+
+	// Leeway allows for clock skew when checking "exp" and "nbf".
+	// Default value is 0.
+	Leeway time.Duration
+
+	// ErrorHandler writes the response for requests that fail verification.
+	// Default is a 401 with a WWW-Authenticate header.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+// JWT verifies the token of every request and stores its claims in the
+// request context, where JWTClaims reads them. Requests without a valid token
+// are passed to the ErrorHandler instead of the next handler.
+func JWT(config JWTConfig) func(http.Handler) http.Handler {
+	if config.Keys == nil {
+		panic("jwt: JWTConfig.Keys is required")
+	}
+	if config.ErrorHandler == nil {
+		config.ErrorHandler = unauthorized
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := extractToken(r, config.Cookie)
+			if token == "" {
+				config.ErrorHandler(w, r, ErrMissingToken)
+				return
+			}
+
+			claims, err := verify(r.Context(), token, config, time.Now())
+			if err != nil {
+				config.ErrorHandler(w, r, err)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), claimsKey{}, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// JWTClaims returns the claims stored by the JWT middleware, or nil
+func JWTClaims(r *http.Request) Claims {
+	claims, _ := r.Context().Value(claimsKey{}).(Claims)
+	return claims
+}
+
+// unauthorized is the default ErrorHandler
+func unauthorized(w http.ResponseWriter, r *http.Request, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+// extractToken returns the bearer token of the request, falling back to the
+// named cookie
+func extractToken(r *http.Request, cookie string) string {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		return strings.TrimSpace(token)
+	}
+	if cookie != "" {
+		if c, err := r.Cookie(cookie); err == nil {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+// header is the JOSE header of a token
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// verify checks the signature and registered claims of a compact token
+func verify(ctx context.Context, token string, config JWTConfig, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+
+	key, err := config.Keys.Key(ctx, h.Alg, h.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(h.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := validateClaims(claims, config, now); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// decodeSegment decodes a base64url JSON segment into v
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrMalformedToken
+	}
+	return nil
+}
+
+// hashes maps the size suffix of an algorithm name to its hash function
+var hashes = map[string]crypto.Hash{
+	"256": crypto.SHA256,
+	"384": crypto.SHA384,
+	"512": crypto.SHA512,
+}
+
+// verifySignature checks signature against the signing input. The key type
+// must match the algorithm family, so an RSA public key can't be abused as an
+// HMAC secret and "none" is never accepted.
+func verifySignature(alg string, key any, input string, signature []byte) error {
+	if alg == "EdDSA" {
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return ErrUnsupportedAlg
+		}
+		if !ed25519.Verify(pub, []byte(input), signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+
+	if len(alg) != 5 {
+		return ErrUnsupportedAlg
+	}
+	hash, ok := hashes[alg[2:]]
+	if !ok {
+		return ErrUnsupportedAlg
+	}
+
+	switch alg[:2] {
+	case "HS":
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrUnsupportedAlg
+		}
+		mac := hmac.New(hash.New, secret)
+		mac.Write([]byte(input))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+
+	digest := hash.New()
+	digest.Write([]byte(input))
+	sum := digest.Sum(nil)
+
+	switch alg[:2] {
+	case "RS", "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrUnsupportedAlg
+		}
+		var err error
+		if alg[0] == 'R' {
+			err = rsa.VerifyPKCS1v15(pub, hash, sum, signature)
+		} else {
+			err = rsa.VerifyPSS(pub, hash, sum, signature, nil)
+		}
+		if err != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	case "ES":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return ErrUnsupportedAlg
+		}
+		// JWS signatures are the fixed-size concatenation of r and s
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return ErrInvalidSignature
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, sum, r, s) {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+	return ErrUnsupportedAlg
+}
+
+// validateClaims checks the exp, nbf, iss and aud claims
+func validateClaims(claims Claims, config JWTConfig, now time.Time) error {
+	if exp, ok, err := numericDate(claims, "exp"); err != nil {
+		return err
+	} else if ok && !now.Before(exp.Add(config.Leeway)) {
+		return ErrTokenExpired
+	}
+	if nbf, ok, err := numericDate(claims, "nbf"); err != nil {
+		return err
+	} else if ok && now.Add(config.Leeway).Before(nbf) {
+		return ErrTokenNotYetValid
+	}
+
+	if config.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != config.Issuer {
+			return ErrInvalidIssuer
+		}
+	}
+	if config.Audience != "" && !slices.Contains(audience(claims), config.Audience) {
+		return ErrInvalidAudience
+	}
+	return nil
+}
+
+// numericDate reads a NumericDate claim, reporting whether it is present
+func numericDate(claims Claims, name string) (time.Time, bool, error) {
+	value, ok := claims[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	seconds, ok := value.(float64)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("%w: %q is not a number", ErrMalformedToken, name)
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))), true, nil
+}
+
+// audience returns the "aud" claim, which may be a string or a list
+func audience(claims Claims) []string {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []any:
+		values := make([]string, 0, len(aud))
+		for _, v := range aud {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrUnknownKey is returned when no key matches a token's key ID
+var ErrUnknownKey = errors.New("jwt: unknown signing key")
+
+// KeySource provides the key used to verify a token, given the token's "alg"
+// and "kid" headers. Keys are []byte for HMAC, *rsa.PublicKey,
+// *ecdsa.PublicKey or ed25519.PublicKey.
+type KeySource interface {
+	Key(ctx context.Context, alg, kid string) (any, error)
+}
+
+// KeySourceFunc adapts a function to the KeySource interface
+type KeySourceFunc func(ctx context.Context, alg, kid string) (any, error)
+
+// Key calls f(ctx, alg, kid)
+func (f KeySourceFunc) Key(ctx context.Context, alg, kid string) (any, error) {
+	return f(ctx, alg, kid)
+}
+
+// HMACKey verifies HS256, HS384 and HS512 tokens with a shared secret
+func HMACKey(secret []byte) KeySource {
+	return KeySourceFunc(func(context.Context, string, string) (any, error) {
+		return secret, nil
+	})
+}
+
+// PublicKey verifies tokens with an *rsa.PublicKey (RS and PS algorithms), an
+// *ecdsa.PublicKey (ES algorithms) or an ed25519.PublicKey (EdDSA)
+func PublicKey(key crypto.PublicKey) KeySource {
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+	default:
+		panic(fmt.Sprintf("jwt: unsupported public key type %T", key))
+	}
+	return KeySourceFunc(func(context.Context, string, string) (any, error) {
+		return key, nil
+	})
+}
+
+// JWKS verifies tokens with the keys published at a JSON Web Key Set URL. The
+// set is cached for ttl, and fetched again early when a token names a key ID
+// that isn't in the cache, so rotated keys are picked up.
+func JWKS(url string, ttl time.Duration) KeySource {
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	return &jwks{
+		url:    url,
+		ttl:    ttl,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// minRefreshInterval limits fetches triggered by unknown key IDs or failed
+// fetches, so tokens with made-up IDs or an unavailable endpoint can't make the
+// middleware hammer the JWKS endpoint
+const minRefreshInterval = 30 * time.Second
+
+// jwks is a KeySource backed by a cached JSON Web Key Set
+type jwks struct {
+	url    string
+	ttl    time.Duration
+	client *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]any
+	fetched time.Time
+
+	// attempted is when the last fetch started, and err its result
+	attempted time.Time
+	err       error
+
+	// refreshing is closed when the fetch in progress finishes, and nil when
+	// there is none
+	refreshing chan struct{}
+}
+
+// Key returns the cached key with the given ID, refreshing the set if needed
+func (s *jwks) Key(ctx context.Context, alg, kid string) (any, error) {
+	s.mu.Lock()
+	_, ok := s.lookup(kid)
+	fresh := time.Since(s.fetched) <= s.ttl
+	s.mu.Unlock()
+
+	var err error
+	if !ok || !fresh {
+		// A cached key is good enough while the set is being refreshed, and
+		// stays in use if the endpoint is briefly unavailable
+		err = s.refresh(ctx, !ok)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, ErrUnknownKey
+}
+
+// refresh fetches the key set, unless the last attempt was too recent. Only one
+// fetch runs at a time, without holding the lock. Callers that need its result
+// wait for it, and others return at once.
+func (s *jwks) refresh(ctx context.Context, wait bool) error {
+	s.mu.Lock()
+	if done := s.refreshing; done != nil {
+		s.mu.Unlock()
+		if !wait {
+			return nil
+		}
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.err
+	}
+	if time.Since(s.attempted) < min(s.ttl, minRefreshInterval) {
+		defer s.mu.Unlock()
+		return s.err
+	}
+	done := make(chan struct{})
+	s.refreshing = done
+	s.attempted = time.Now()
+	s.mu.Unlock()
+
+	// The fetch is shared, so it isn't canceled with the request starting it
+	keys, err := s.fetch(context.WithoutCancel(ctx))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		s.keys = keys
+		s.fetched = time.Now()
+	}
+	s.err = err
+	s.refreshing = nil
+	close(done)
+	return err
+}
+
+// lookup finds a key by ID. Tokens without an ID match a set of one key.
+func (s *jwks) lookup(kid string) (any, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// jsonWebKey is a single key of a JSON Web Key Set
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetch downloads the key set, skipping keys it can't use
+func (s *jwks) fetch(ctx context.Context) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: fetching JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwt: fetching JWKS: unexpected status %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("jwt: decoding JWKS: %w", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// curves maps JWK curve names to elliptic curves
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+// publicKey decodes the key material of an RSA, EC or Ed25519 key
+func (k jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, errors.New("jwt: invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		curve, ok := curves[k.Crv]
+		if !ok {
+			return nil, fmt.Errorf("jwt: unsupported curve %q", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("jwt: unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("jwt: invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("jwt: unsupported key type %q", k.Kty)
+}
+
+// decodeInt decodes a base64url big-endian integer
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("jwt: invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
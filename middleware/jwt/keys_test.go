@@ -0,0 +1,88 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jwksServer publishes one Ed25519 key with ID "a", or fails while down is set
+func jwksServer(t *testing.T, down *atomic.Bool, hits *atomic.Int64) string {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := base64.RawURLEncoding.EncodeToString(pub)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if down.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"a","x":%q}]}`, x)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestJWKSFailedFetchRateLimited(t *testing.T) {
+	var down atomic.Bool
+	var hits atomic.Int64
+	down.Store(true)
+	source := JWKS(jwksServer(t, &down, &hits), time.Hour).(*jwks)
+
+	for range 5 {
+		if _, err := source.Key(context.Background(), "EdDSA", "a"); err == nil || err == ErrUnknownKey {
+			t.Fatalf("Key with the endpoint down: err = %v, want the fetch error", err)
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("endpoint fetched %d times while down, want 1", n)
+	}
+
+	// Once the retry interval has passed, the next request fetches again
+	down.Store(false)
+	source.mu.Lock()
+	source.attempted = time.Now().Add(-minRefreshInterval)
+	source.mu.Unlock()
+	if _, err := source.Key(context.Background(), "EdDSA", "a"); err != nil {
+		t.Fatalf("Key after recovery: %v", err)
+	}
+	if _, err := source.Key(context.Background(), "EdDSA", "b"); err != ErrUnknownKey {
+		t.Errorf("unknown key ID: err = %v, want ErrUnknownKey", err)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Errorf("endpoint fetched %d times, want 2", n)
+	}
+}
+
+func TestJWKSCachedKeyDuringRefresh(t *testing.T) {
+	var down atomic.Bool
+	var hits atomic.Int64
+	source := JWKS(jwksServer(t, &down, &hits), time.Hour).(*jwks)
+	if _, err := source.Key(context.Background(), "EdDSA", "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// With a refresh in progress, a cached key is returned without waiting
+	source.mu.Lock()
+	source.fetched = time.Now().Add(-2 * time.Hour)
+	source.refreshing = make(chan struct{})
+	source.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := source.Key(ctx, "EdDSA", "a"); err != nil {
+		t.Errorf("cached key during a refresh: %v", err)
+	}
+	if _, err := source.Key(ctx, "EdDSA", "b"); err != context.DeadlineExceeded {
+		t.Errorf("unknown key during a refresh: err = %v, want to wait for it", err)
+	}
+}
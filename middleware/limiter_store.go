@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// LimiterStore keeps the request counts of the rate limiter. Implementations
+// must be safe for concurrent use; a store shared by several instances, such
+// as one backed by Redis, enforces a global limit.
+type LimiterStore interface {
+	// Allow records a request for key and reports whether it is within the limit
+	Allow(ctx context.Context, key string) (LimitResult, error)
+}
+
+// LimitResult is the outcome of a LimiterStore.Allow call
+type LimitResult struct {
+	// Allowed reports whether the request is within the limit
+	Allowed bool
+
+	// Limit is the number of requests allowed per window
+	Limit int
+
+	// Remaining is the number of requests left for the key
+	Remaining int
+
+	// RetryAfter is how long until the next request would be allowed. It is
+	// only meaningful when Allowed is false.
+	RetryAfter time.Duration
+
+	// Reset is how long until the key's full limit is available again
+	Reset time.Duration
+}
+
+// MemoryLimiterStore is an in-memory token bucket LimiterStore. Each key gets
+// a bucket holding the limit's tokens that refills over the window. Stale keys
+// are evicted lazily as requests come in, so it doesn't keep a background
+// goroutine alive. It only limits the requests of a single process.
+type MemoryLimiterStore struct {
+	capacity        float64
+	refillPerSecond float64
+	ttl             time.Duration
+	cleanupInterval time.Duration
+
+	mu          sync.Mutex
+	buckets     map[string]*bucket
+	lastCleanup time.Time
+}
+
+// bucket tracks the tokens left for a single client
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// NewMemoryLimiterStore creates an in-memory store allowing requests per
+// window for each key. Keys are forgotten after a minute of inactivity, or
+// after window if that is longer.
+func NewMemoryLimiterStore(requests int, window time.Duration) *MemoryLimiterStore {
+	return newMemoryLimiterStore(RateLimiterConfig{Requests: requests, Window: window})
+}
+
+// newMemoryLimiterStore creates the default store of a rate limiter, applying
+// the defaults of the config's store fields
+func newMemoryLimiterStore(config RateLimiterConfig) *MemoryLimiterStore {
+	if config.Requests <= 0 {
+		config.Requests = 1
+	}
+	if config.Window <= 0 {
+		config.Window = time.Second
+	}
+	if config.TTL <= 0 {
+		config.TTL = time.Minute
+	}
+	// Forgetting a client before its bucket has refilled would reset its limit
+	if config.TTL < config.Window {
+		config.TTL = config.Window
+	}
+	if config.CleanupInterval <= 0 {
+		config.CleanupInterval = time.Minute
+	}
+
+	capacity := float64(config.Requests)
+	return &MemoryLimiterStore{
+		capacity:        capacity,
+		refillPerSecond: capacity / config.Window.Seconds(),
+		ttl:             config.TTL,
+		cleanupInterval: config.CleanupInterval,
+		buckets:         make(map[string]*bucket),
+		lastCleanup:     time.Now(),
+	}
+}
+
+// Allow takes a token from the key's bucket if one is left. It never fails.
+func (s *MemoryLimiterStore) Allow(ctx context.Context, key string) (LimitResult, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	// Evict clients that haven't been seen for longer than the TTL
+	if now.Sub(s.lastCleanup) >= s.cleanupInterval {
+		for k, b := range s.buckets {
+			if now.Sub(b.lastSeen) > s.ttl {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastCleanup = now
+	}
+
+	b, exists := s.buckets[key]
+	if !exists {
+		b = &bucket{tokens: s.capacity, lastSeen: now}
+		s.buckets[key] = b
+	}
+
+	// Refill for the time elapsed since the client's last request
+	b.tokens = math.Min(s.capacity, b.tokens+now.Sub(b.lastSeen).Seconds()*s.refillPerSecond)
+	b.lastSeen = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	tokens := b.tokens
+	s.mu.Unlock()
+
+	result := LimitResult{
+		Allowed:   allowed,
+		Limit:     int(s.capacity),
+		Remaining: int(tokens),
+		Reset:     s.duration(s.capacity - tokens),
+	}
+	if !allowed {
+		result.RetryAfter = s.duration(1 - tokens)
+	}
+	return result, nil
+}
+
+// duration returns how long the bucket takes to refill the given tokens
+func (s *MemoryLimiterStore) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / s.refillPerSecond * float64(time.Second))
+}
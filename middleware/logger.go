@@ -6,37 +6,50 @@ import (
 	"net/http"
 	"os"
 	"time"
+
+	"github.com/jtclarkjr/router-go/internal/routectx"
 )
 
 // LoggerConfig holds configuration options for the logger middleware
 type LoggerConfig struct {
 	IncludeTimestamp bool
 	Output           io.Writer // Defaults to os.Stderr if nil
+	UseRoutePattern  bool      // Log the matched route pattern (e.g. /users/{id}) instead of the request path
+	NoColor          bool      // Disable colors even when the output is a terminal
 }
 
-// Middleware for logging requests with colorful output and response time (timestamp optional)
+// Middleware for logging requests with colorful output, response size and response time (timestamp optional)
 func Logger(next http.Handler) http.Handler {
 	return LoggerWithConfig(LoggerConfig{IncludeTimestamp: true})(next) // Default to including timestamps
 }
 
-// LoggerWithConfig creates a logging middleware with custom configuration
+// LoggerWithOutput creates a logging middleware that writes to w, with timestamps
+func LoggerWithOutput(w io.Writer) func(http.Handler) http.Handler {
+	return LoggerWithConfig(LoggerConfig{IncludeTimestamp: true, Output: w})
+}
+
+// LoggerWithConfig creates a logging middleware with custom configuration.
+// Colors are used when the output is a terminal, or as set by SetColorOutput,
+// and never when NoColor is set.
 func LoggerWithConfig(config LoggerConfig) func(http.Handler) http.Handler {
+	// Configure logger based on config
+	output := config.Output
+	if output == nil {
+		output = os.Stderr
+	}
+	logger := log.New(output, "", 0)
+	if config.IncludeTimestamp {
+		logger.SetFlags(log.LstdFlags) // Set standard flags (date and time)
+	}
+	terminal := isTerminal(output)
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Configure logger based on config
-			output := config.Output
-			if output == nil {
-				output = os.Stderr
-			}
-			logger := log.New(output, "", 0)
-			if config.IncludeTimestamp {
-				logger.SetFlags(log.LstdFlags) // Set standard flags (date and time)
-			}
-
 			start := time.Now() // Start timing
-			wrappedWriter := &ResponseWriterWrapper{ResponseWriter: w, StatusCode: http.StatusOK}
+			wrappedWriter := NewResponseWriter(w)
 
-			// Process the request
+			// Process the request, collecting any error attached with WithError
+			r = withErrorSlot(r)
 			next.ServeHTTP(wrappedWriter, r)
 
 			// Calculate response time
@@ -47,33 +60,50 @@ func LoggerWithConfig(config LoggerConfig) func(http.Handler) http.Handler {
 			statusColor := getStatusColor(wrappedWriter.StatusCode)
 			methodColor := getMethodColor(r.Method)
 			resetColor := "\033[0m"
+			errorColor := "\033[31m" // Red
+			if config.NoColor || !colorEnabled(terminal) {
+				durationColor, statusColor, methodColor, resetColor, errorColor = "", "", "", "", ""
+			}
 
-			// Check for error message in context
-			var errorMsg string
-			type ctxKey string
-			if v := r.Context().Value(ctxKey("envvar_error")); v != nil {
-				if s, ok := v.(string); ok && s != "" {
-					errorMsg = s
+			path := r.URL.Path
+			if config.UseRoutePattern {
+				if pattern := routectx.Pattern(r.Context()); pattern != "" {
+					path = pattern
 				}
 			}
 
+			// Check for an error attached to the request
+			var errorMsg string
+			if err := ErrorFromContext(r); err != nil {
+				errorMsg = err.Error()
+			}
+
+			// Prefix the line with the request ID when the RequestID middleware set one
+			var requestID string
+			if id := GetRequestID(r); id != "" {
+				requestID = "[" + id + "] "
+			}
+
 			// Log the request with colors and response time, and error if present
 			if errorMsg != "" {
-				errorColor := "\033[31m" // Red
-				logger.Printf("%s%s%s %s%s%s from %s - %s%d%s in %s%s%s | %sERROR: %s%s",
+				logger.Printf("%s%s%s%s %s%s%s from %s - %s%d%s %dB in %s%s%s | %sERROR: %s%s",
+					requestID,
 					methodColor, r.Method, resetColor,
-					statusColor, r.URL.Path, resetColor,
+					statusColor, path, resetColor,
 					r.RemoteAddr,
 					statusColor, wrappedWriter.StatusCode, resetColor,
+					wrappedWriter.BytesWritten,
 					durationColor, duration, resetColor,
 					errorColor, errorMsg, resetColor,
 				)
 			} else {
-				logger.Printf("%s%s%s %s%s%s from %s - %s%d%s in %s%s%s",
+				logger.Printf("%s%s%s%s %s%s%s from %s - %s%d%s %dB in %s%s%s",
+					requestID,
 					methodColor, r.Method, resetColor,
-					statusColor, r.URL.Path, resetColor,
+					statusColor, path, resetColor,
 					r.RemoteAddr,
 					statusColor, wrappedWriter.StatusCode, resetColor,
+					wrappedWriter.BytesWritten,
 					durationColor, duration, resetColor,
 				)
 			}
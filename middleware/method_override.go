@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// MethodOverride lets POST requests stand in for PUT, PATCH and DELETE, which
+// HTML forms can't send. The method is taken from the X-HTTP-Method-Override
+// header, or else from the _method field of a URL-encoded or multipart form
+// body, which is parsed into r.PostForm to read it. Overrides on other
+// methods, or to any other method, are ignored. Routing happens before route
+// middleware runs, so wrap the router:
+//
+//	http.ListenAndServe(":8080", middleware.MethodOverride(r))
+func MethodOverride(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		method := r.Header.Get("X-HTTP-Method-Override")
+		if method == "" && isFormBody(r) {
+			method = r.PostFormValue("_method")
+		}
+
+		switch method = strings.ToUpper(strings.TrimSpace(method)); method {
+		case http.MethodPut, http.MethodPatch, http.MethodDelete:
+			overridden := r.WithContext(r.Context())
+			overridden.Method = method
+			next.ServeHTTP(w, overridden)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// isFormBody reports whether the request body is an HTML form submission
+func isFormBody(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && (mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data")
+}
@@ -0,0 +1,66 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	router "github.com/jtclarkjr/router-go"
+	"github.com/jtclarkjr/router-go/middleware"
+	"github.com/jtclarkjr/router-go/routertest"
+)
+
+// overrideRouter answers every method on /posts/{id} with the method routed
+// to, and the form title for PUT
+func overrideRouter() http.Handler {
+	r := router.NewRouter()
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		r.Handle(method, "/posts/{id}", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(req.Method + " " + req.PostFormValue("title")))
+		}))
+	}
+	return middleware.MethodOverride(r)
+}
+
+func TestMethodOverrideHeader(t *testing.T) {
+	tests := []struct {
+		method   string
+		override string
+		want     string
+	}{
+		{http.MethodPost, "DELETE", "DELETE "},
+		{http.MethodPost, "patch", "PATCH "},
+		// Only PUT, PATCH and DELETE can be reached
+		{http.MethodPost, "GET", "POST "},
+		{http.MethodPost, "CONNECT", "POST "},
+		// Only POST is overridden
+		{http.MethodGet, "DELETE", "GET "},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/posts/1", nil)
+		req.Header.Set("X-HTTP-Method-Override", tt.override)
+		if got := routertest.Do(overrideRouter(), req).Body.String(); got != tt.want {
+			t.Errorf("%s overridden to %s: served %q, want %q", tt.method, tt.override, got, tt.want)
+		}
+	}
+}
+
+func TestMethodOverrideFormField(t *testing.T) {
+	form := url.Values{"_method": {"PUT"}, "title": {"Hello"}}
+	req := httptest.NewRequest(http.MethodPost, "/posts/1", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	// The form stays readable by the handler after the override is parsed
+	if got := routertest.Do(overrideRouter(), req).Body.String(); got != "PUT Hello" {
+		t.Errorf("served %q, want %q", got, "PUT Hello")
+	}
+
+	// A _method field in a body that isn't a form is ignored
+	req = httptest.NewRequest(http.MethodPost, "/posts/1", strings.NewReader("_method=DELETE"))
+	req.Header.Set("Content-Type", "text/plain")
+	if got := routertest.Do(overrideRouter(), req).Body.String(); got != "POST " {
+		t.Errorf("served %q, want %q", got, "POST ")
+	}
+}
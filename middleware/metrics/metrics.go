@@ -0,0 +1,115 @@
+// Package metrics provides a middleware that records Prometheus request
+// metrics per route. It lives in its own package so routers that don't use
+// it don't depend on the Prometheus client.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	router "github.com/jtclarkjr/router-go"
+	"github.com/jtclarkjr/router-go/middleware"
+)
+
+// unmatchedRoute labels requests that didn't match a route, such as those
+// served by the NotFound handler
+const unmatchedRoute = "unmatched"
+
+// Config defines the configuration for the metrics collector
+type Config struct {
+	// Namespace and Subsystem prefix the metric names
+	Namespace string
+	Subsystem string
+
+	// Buckets are the latency histogram buckets in seconds.
+	// Default value is prometheus.DefBuckets.
+	Buckets []float64
+}
+
+// Collector records a request counter and a latency histogram labelled by
+// method, route pattern and status code. It implements prometheus.Collector,
+// so it can be registered with any registry.
+type Collector struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// New creates a collector with the provided configuration. Register it before
+// serving, e.g. prometheus.MustRegister(collector).
+func New(config Config) *Collector {
+	if len(config.Buckets) == 0 {
+		config.Buckets = prometheus.DefBuckets
+	}
+	labels := []string{"method", "route", "status"}
+
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: config.Namespace,
+			Subsystem: config.Subsystem,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests served, by method, route and status.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: config.Namespace,
+			Subsystem: config.Subsystem,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of HTTP requests in seconds, by method, route and status.",
+			Buckets:   config.Buckets,
+		}, labels),
+	}
+}
+
+// Describe implements the prometheus.Collector interface
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.duration.Describe(ch)
+}
+
+// Collect implements the prometheus.Collector interface
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.duration.Collect(ch)
+}
+
+// Middleware records the metrics of every request it serves. Requests are
+// labelled with the matched route pattern, such as /users/{id}, rather than
+// the raw path, so label cardinality stays bounded.
+func (c *Collector) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		wrappedWriter := middleware.NewResponseWriter(w)
+
+		next.ServeHTTP(wrappedWriter, r)
+
+		route := router.MatchedRoutePattern(r)
+		if route == "" {
+			route = unmatchedRoute
+		}
+		labels := prometheus.Labels{
+			"method": r.Method,
+			"route":  route,
+			"status": strconv.Itoa(wrappedWriter.StatusCode),
+		}
+		c.requests.With(labels).Inc()
+		c.duration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+var (
+	defaultCollector *Collector
+	registerOnce     sync.Once
+)
+
+// Metrics records request metrics with a collector registered on the default
+// Prometheus registry, which promhttp.Handler() exposes
+func Metrics(next http.Handler) http.Handler {
+	registerOnce.Do(func() {
+		defaultCollector = New(Config{})
+		prometheus.MustRegister(defaultCollector)
+	})
+	return defaultCollector.Middleware(next)
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import "net/http"
+
+// noCacheHeaders are set on every response by NoCache
+var noCacheHeaders = map[string]string{
+	"Cache-Control": "no-cache, no-store, must-revalidate",
+	"Pragma":        "no-cache",
+	"Expires":       "0",
+}
+
+// conditionalHeaders are removed from requests by NoCache
+var conditionalHeaders = []string{
+	"If-Match",
+	"If-Modified-Since",
+	"If-None-Match",
+	"If-Range",
+	"If-Unmodified-Since",
+}
+
+// NoCache is a middleware that stops clients and proxies from caching
+// responses. It sets Cache-Control, Pragma and Expires, and removes the
+// conditional headers from the request so handlers never answer with a 304.
+func NoCache(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, name := range conditionalHeaders {
+			r.Header.Del(name)
+		}
+
+		for name, value := range noCacheHeaders {
+			w.Header().Set(name, value)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jtclarkjr/router-go/routertest"
+)
+
+func TestNoCache(t *testing.T) {
+	conditional := map[string]string{
+		"If-Match":            `"abc"`,
+		"If-None-Match":       `"abc"`,
+		"If-Modified-Since":   "Mon, 02 Jan 2006 15:04:05 GMT",
+		"If-Unmodified-Since": "Mon, 02 Jan 2006 15:04:05 GMT",
+		"If-Range":            `"abc"`,
+	}
+	var seen []string
+	h := NoCache(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name := range conditional {
+			if r.Header.Get(name) != "" {
+				seen = append(seen, name)
+			}
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	for name, value := range conditional {
+		req.Header.Set(name, value)
+	}
+	rec := routertest.Do(h, req)
+
+	want := map[string]string{
+		"Cache-Control": "no-cache, no-store, must-revalidate",
+		"Pragma":        "no-cache",
+		"Expires":       "0",
+	}
+	for name, value := range want {
+		if got := rec.Header().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	if len(seen) > 0 {
+		t.Errorf("handler saw conditional headers %v", seen)
+	}
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import "net/http"
+
+// OnError calls fn after the handler completes if the response got an error
+// status (400 or above), e.g. to alert or log extra details. The response has
+// been written by then, so headers set through w are no longer sent.
+//
+// Behind Logger, or any middleware using NewResponseWriter, the existing
+// wrapper is reused instead of adding another. Responses written by
+// middleware that runs before OnError aren't seen, so register it ahead of
+// Recoverer to observe the 500s of recovered panics.
+func OnError(fn func(w http.ResponseWriter, r *http.Request, status int)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := NewResponseWriter(w)
+			next.ServeHTTP(rw, r)
+			if rw.StatusCode >= http.StatusBadRequest {
+				fn(rw, r, rw.StatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jtclarkjr/router-go/routertest"
+)
+
+func TestOnError(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    int
+	}{
+		{"not found", func(w http.ResponseWriter, r *http.Request) { http.NotFound(w, r) }, http.StatusNotFound},
+		{"server error", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) }, http.StatusInternalServerError},
+		{"ok", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }, 0},
+		{"redirect", func(w http.ResponseWriter, r *http.Request) { http.Redirect(w, r, "/", http.StatusFound) }, 0},
+		{"nothing written", func(w http.ResponseWriter, r *http.Request) {}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []string
+			got := 0
+			h := OnError(func(w http.ResponseWriter, r *http.Request, status int) {
+				events = append(events, "callback")
+				got = status
+			})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.handler(w, r)
+				events = append(events, "handler done")
+			}))
+
+			routertest.Get(h, "/")
+			if got != tt.want {
+				t.Errorf("callback status = %d, want %d", got, tt.want)
+			}
+			if tt.want != 0 && (len(events) != 2 || events[0] != "handler done") {
+				t.Errorf("events = %v, want the callback once after the handler", events)
+			}
+		})
+	}
+}
+
+func TestOnErrorReusesResponseWriter(t *testing.T) {
+	var outer, inner *ResponseWriter
+	h := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			outer = NewResponseWriter(w)
+			next.ServeHTTP(outer, r)
+		})
+	}(OnError(func(w http.ResponseWriter, r *http.Request, status int) {})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inner, _ = w.(*ResponseWriter)
+			w.WriteHeader(http.StatusTeapot)
+		}),
+	))
+
+	routertest.Get(h, "/")
+	if inner == nil || inner != outer {
+		t.Error("OnError wrapped the response writer again instead of reusing it")
+	}
+	if outer.StatusCode != http.StatusTeapot {
+		t.Errorf("StatusCode = %d, want %d", outer.StatusCode, http.StatusTeapot)
+	}
+}
@@ -1,29 +1,153 @@
 package middleware
 
 import (
+	"fmt"
+	"log"
+	"math"
 	"net/http"
-	"sync"
+	"strconv"
 	"time"
 )
 
+// RateLimiterConfig defines the configuration for the rate limiter middleware
+type RateLimiterConfig struct {
+	// Requests is the number of requests a client may make per Window. It is
+	// also the burst size. Default value is 1. Requests, Window, TTL and
+	// CleanupInterval configure the in-memory store and are ignored when
+	// Store is set.
+	Requests int
+
+	// Window is the interval over which Requests are allowed.
+	// Default value is 1 second.
+	Window time.Duration
+
+	// TTL is how long a client is remembered after its last request. It is
+	// never shorter than Window. Default value is 1 minute.
+	TTL time.Duration
+
+	// CleanupInterval is how often entries older than TTL are evicted.
+	// Default value is 1 minute.
+	CleanupInterval time.Duration
+
+	// KeyFunc returns the key a request is limited by, e.g. an API token.
+	// Default is the client IP, without the port. Requests for which it
+	// returns "" are limited by client IP, or by ShareEmptyKey.
+	KeyFunc func(r *http.Request) string
+
+	// ShareEmptyKey makes all requests for which KeyFunc returns "" share a
+	// single bucket, rather than falling back to one per client IP
+	ShareEmptyKey bool
+
+	// TrustedProxies lists proxy IPs or CIDR ranges (e.g. "10.0.0.0/8") whose
+	// X-Forwarded-For and X-Real-IP headers are believed by the default
+	// KeyFunc. Headers from any other address are ignored to prevent spoofing.
+	TrustedProxies []string
+
+	// JSON makes rejected requests get {"error":"too many requests",
+	// "retry_after":N} with an application/json content type instead of a
+	// plain text body
+	JSON bool
+
+	// LimitHandler writes the response for rejected requests, the headers
+	// including Retry-After already being set. retryAfter is the time until
+	// the client's next request would be allowed. It takes precedence over
+	// JSON.
+	LimitHandler func(w http.ResponseWriter, r *http.Request, retryAfter time.Duration)
+
+	// Store keeps the limits, e.g. in Redis so they are shared by every
+	// instance. Default is an in-memory store local to the middleware.
+	Store LimiterStore
+
+	// FailOpen lets requests through when Store returns an error. By default
+	// they are rejected with a 503.
+	FailOpen bool
+}
+
 // RateLimiter is a middleware that limits the number of requests per second
 func RateLimiter(next http.Handler) http.Handler {
-	var lastRequestTime = make(map[string]time.Time)
-	var mu sync.Mutex
-
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		clientIP := r.RemoteAddr
-		mu.Lock()
-		defer mu.Unlock()
-
-		now := time.Now()
-		if lastTime, exists := lastRequestTime[clientIP]; exists {
-			if now.Sub(lastTime) < time.Second {
-				http.Error(w, "Too many requests", http.StatusTooManyRequests)
-				return
+	return RateLimiterWithConfig(RateLimiterConfig{})(next)
+}
+
+// RateLimiterByKey limits each key returned by keyFunc, such as an API token
+// or a user ID set by an upstream auth middleware, to limit requests per
+// window. Requests without a key are limited by client IP.
+func RateLimiterByKey(keyFunc func(r *http.Request) string, limit int, window time.Duration) func(http.Handler) http.Handler {
+	return RateLimiterWithConfig(RateLimiterConfig{
+		Requests: limit,
+		Window:   window,
+		KeyFunc:  keyFunc,
+	})
+}
+
+// RateLimiterWithConfig creates a rate limiting middleware with custom configuration.
+// Unless a Store is given, each client gets an in-memory token bucket holding
+// Requests tokens that refills over Window. Every response carries
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset (the Unix
+// time at which the bucket is full again), and rejected requests get a 429
+// with Retry-After, rounded up to whole seconds.
+//
+// When the store fails, the error is logged and the request is rejected with
+// a 503, or let through if FailOpen is set.
+func RateLimiterWithConfig(config RateLimiterConfig) func(http.Handler) http.Handler {
+	if config.Store == nil {
+		config.Store = newMemoryLimiterStore(config)
+	}
+
+	trusted := parseTrustedProxies(config.TrustedProxies)
+	if config.KeyFunc == nil {
+		config.KeyFunc = func(r *http.Request) string {
+			return clientIP(r, trusted)
+		}
+	} else {
+		// Keep the fallback buckets apart from the caller's keys
+		keyFunc := config.KeyFunc
+		config.KeyFunc = func(r *http.Request) string {
+			if key := keyFunc(r); key != "" {
+				return "key:" + key
+			}
+			if config.ShareEmptyKey {
+				return "shared"
 			}
+			return "ip:" + clientIP(r, trusted)
 		}
-		lastRequestTime[clientIP] = now
-		next.ServeHTTP(w, r)
-	})
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := config.Store.Allow(r.Context(), config.KeyFunc(r))
+			if err != nil {
+				log.Printf("[RateLimiter] store: %v", err)
+				if config.FailOpen {
+					next.ServeHTTP(w, r)
+					return
+				}
+				w.Header().Set("Retry-After", "1")
+				http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(result.Reset).Unix(), 10))
+
+			if !result.Allowed {
+				retryAfter := int(math.Max(1, math.Ceil(result.RetryAfter.Seconds())))
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+				switch {
+				case config.LimitHandler != nil:
+					config.LimitHandler(w, r, result.RetryAfter)
+				case config.JSON:
+					w.Header().Set("Content-Type", "application/json")
+					w.Header().Set("X-Content-Type-Options", "nosniff")
+					w.WriteHeader(http.StatusTooManyRequests)
+					fmt.Fprintf(w, `{"error":"too many requests","retry_after":%d}`+"\n", retryAfter)
+				default:
+					http.Error(w, "Too many requests", http.StatusTooManyRequests)
+				}
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,30 @@
+package middleware
+
+import "net/http"
+
+// RealIP sets r.RemoteAddr to the client IP for requests that come from one of
+// the trusted proxies, given as IP addresses or CIDR ranges such as
+// "10.0.0.0/8". The client IP is the rightmost X-Forwarded-For hop that isn't
+// itself a trusted proxy, or X-Real-IP. Requests from any other address keep
+// their RemoteAddr, so clients can't spoof their IP by sending the headers
+// themselves. With RealIP in front, middleware such as RateLimiter and Logger
+// see the real client. It panics on an invalid proxy entry.
+func RealIP(trustedProxies ...string) func(http.Handler) http.Handler {
+	trusted := parseTrustedProxies(trustedProxies)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ip := clientIP(r, trusted); ip != remoteIP(r) {
+				r.RemoteAddr = ip
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientIP returns the IP of the client that sent r, without a port. Behind
+// RealIP this is the client IP it established from the trusted proxy headers,
+// otherwise the remote address.
+func ClientIP(r *http.Request) string {
+	return remoteIP(r)
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jtclarkjr/router-go/routertest"
+)
+
+func TestRealIP(t *testing.T) {
+	h := RealIP("10.0.0.0/8", "192.168.1.1")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(ClientIP(r)))
+	}))
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{"direct client", "203.0.113.7:1234", nil, "", "203.0.113.7"},
+		{"proxy forwarding", "10.1.2.3:1234", []string{"203.0.113.7"}, "", "203.0.113.7"},
+		{"single trusted proxy", "192.168.1.1:80", []string{"203.0.113.7"}, "", "203.0.113.7"},
+		{"chain of proxies", "10.1.2.3:1234", []string{"203.0.113.7, 10.9.9.9"}, "", "203.0.113.7"},
+		{"split headers", "10.1.2.3:1234", []string{"203.0.113.7", "10.9.9.9"}, "", "203.0.113.7"},
+		// The client prepends a fake hop; the rightmost untrusted one is used
+		{"spoofed hop via proxy", "10.1.2.3:1234", []string{"1.1.1.1, 203.0.113.7"}, "", "203.0.113.7"},
+		{"real ip header", "10.1.2.3:1234", nil, "203.0.113.7", "203.0.113.7"},
+		{"malformed real ip", "10.1.2.3:1234", nil, "not-an-ip", "10.1.2.3"},
+		{"malformed hop", "10.1.2.3:1234", []string{"203.0.113.7, garbage"}, "", "10.1.2.3"},
+		{"ipv6 proxy", "[::ffff:10.0.0.1]:443", []string{"2001:db8::1"}, "", "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := routertest.Do(h, req).Body.String(); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRealIPIgnoresSpoofedHeaders(t *testing.T) {
+	h := RealIP("10.0.0.0/8")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RemoteAddr))
+	}))
+
+	// An untrusted client can't pick its IP with either header
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.7:1234"
+	req.Header.Set("X-Forwarded-For", "10.0.0.1")
+	req.Header.Set("X-Real-IP", "127.0.0.1")
+	if got := routertest.Do(h, req).Body.String(); got != "203.0.113.7:1234" {
+		t.Errorf("RemoteAddr = %q, want it unchanged", got)
+	}
+}
+
+func TestRealIPInvalidProxyPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("invalid trusted proxy accepted")
+		}
+	}()
+	RealIP("10.0.0.0/33")
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
@@ -17,41 +18,132 @@ const (
 	Reset  = "\033[0m"
 )
 
+// RecovererConfig defines the configuration for the recoverer middleware
+type RecovererConfig struct {
+	// PanicHandler writes the response after a panic, e.g. a JSON error with a
+	// correlation ID. Default is a plain 500 Internal Server Error.
+	PanicHandler func(w http.ResponseWriter, r *http.Request, err any)
+
+	// IncludeStack adds the stack trace to the default 500 response body.
+	// Only enable this for local development, as it exposes internals.
+	IncludeStack bool
+
+	// JSON makes the default response {"error":"internal server error"} with
+	// an application/json content type instead of a plain text body, adding
+	// a "request_id" field when the RequestID middleware has run, and "panic"
+	// and "stack" fields with IncludeStack
+	JSON bool
+
+	// Reporter is called with the request, panic value and stack before the
+	// response is written, e.g. to forward the panic to Sentry. A panic inside
+	// the reporter is logged and otherwise ignored.
+	Reporter func(r *http.Request, err any, stack []byte)
+}
+
 // Recoverer is a middleware that recovers from panics, logs the panic (with a backtrace),
 // and returns a 500 Internal Server Error response.
 func Recoverer(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if err := recover(); err != nil {
-				// Log the panic details
-				logPanic(err)
+	return RecovererWithConfig(RecovererConfig{})(next)
+}
+
+// RecovererWithReporter creates a recovering middleware that passes each panic
+// to reporter before responding with a 500
+func RecovererWithReporter(reporter func(r *http.Request, err any, stack []byte)) func(http.Handler) http.Handler {
+	return RecovererWithConfig(RecovererConfig{Reporter: reporter})
+}
+
+// RecovererWithConfig creates a recovering middleware with custom configuration.
+// The panic is always logged to stderr. http.ErrAbortHandler is re-panicked so
+// net/http can abort the response as intended.
+func RecovererWithConfig(config RecovererConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if err := recover(); err != nil {
+					if err == http.ErrAbortHandler {
+						panic(err)
+					}
+
+					// Log the panic details
+					stack := debug.Stack()
+					logPanic(err, stack)
+
+					if config.Reporter != nil {
+						report(config.Reporter, r, err, stack)
+					}
 
-				// Respond with 500 Internal Server Error
-				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			}
-		}()
-		next.ServeHTTP(w, r)
-	})
+					if config.PanicHandler != nil {
+						config.PanicHandler(w, r, err)
+						return
+					}
+
+					if config.JSON {
+						writePanicJSON(w, r, err, stack, config.IncludeStack)
+						return
+					}
+
+					// Respond with 500 Internal Server Error
+					body := http.StatusText(http.StatusInternalServerError)
+					if config.IncludeStack {
+						body = fmt.Sprintf("%s\n\npanic: %v\n\n%s", body, err, stack)
+					}
+					http.Error(w, body, http.StatusInternalServerError)
+				}
+			}()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writePanicJSON writes the JSON 500 response for a recovered panic
+func writePanicJSON(w http.ResponseWriter, r *http.Request, err any, stack []byte, includeStack bool) {
+	body := map[string]string{"error": "internal server error"}
+	if id := GetRequestID(r); id != "" {
+		body["request_id"] = id
+	}
+	if includeStack {
+		body["panic"] = fmt.Sprint(err)
+		body["stack"] = string(stack)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusInternalServerError)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// report calls the reporter, recovering from any panic inside it so the
+// client still gets a response
+func report(reporter func(r *http.Request, err any, stack []byte), r *http.Request, err any, stack []byte) {
+	defer func() {
+		if reporterErr := recover(); reporterErr != nil {
+			colors := colorEnabled(isTerminal(os.Stderr))
+			fmt.Fprintf(os.Stderr, "%sPANIC in Recoverer reporter: %v%s\n", ansi(colors, Red), reporterErr, ansi(colors, Reset))
+		}
+	}()
+	reporter(r, err, stack)
 }
 
-// logPanic logs the panic details and stack trace to stderr with colored output.
-func logPanic(err any) {
-	stack := debug.Stack()
-	fmt.Fprintf(os.Stderr, "%sPANIC: %v%s\n", Red, err, Reset)
-	fmt.Fprintf(os.Stderr, "%sSTACK TRACE:%s\n%s\n", Yellow, Reset, formatStack(stack))
+// logPanic logs the panic details and stack trace to stderr, colored when
+// stderr is a terminal or SetColorOutput enables it.
+func logPanic(err any, stack []byte) {
+	colors := colorEnabled(isTerminal(os.Stderr))
+	fmt.Fprintf(os.Stderr, "%sPANIC: %v%s\n", ansi(colors, Red), err, ansi(colors, Reset))
+	fmt.Fprintf(os.Stderr, "%sSTACK TRACE:%s\n%s\n", ansi(colors, Yellow), ansi(colors, Reset), formatStack(stack, colors))
 }
 
-// formatStack formats the stack trace for better readability with colored output.
-func formatStack(stack []byte) string {
+// formatStack formats the stack trace for better readability, highlighting
+// source locations when colors is set.
+func formatStack(stack []byte, colors bool) string {
 	lines := strings.Split(string(stack), "\n")
 	var formattedStack bytes.Buffer
 
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
 		if strings.Contains(line, ".go:") {
-			fmt.Fprintf(&formattedStack, "%s  %s%s\n", Cyan, line, Reset)
+			fmt.Fprintf(&formattedStack, "%s  %s%s\n", ansi(colors, Cyan), line, ansi(colors, Reset))
 		} else {
-			fmt.Fprintf(&formattedStack, "%s%s\n", Yellow, line)
+			fmt.Fprintf(&formattedStack, "%s%s\n", ansi(colors, Yellow), line)
 		}
 	}
 
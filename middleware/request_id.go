@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// requestIDKey stores the request ID
+var requestIDKey = NewKey[string]("requestID")
+
+// RequestIDConfig defines the configuration for the request ID middleware
+type RequestIDConfig struct {
+	// Header is read for an incoming ID and set on the response.
+	// Default value is "X-Request-ID".
+	Header string
+
+	// Generator creates an ID when the request doesn't carry a usable one.
+	// Default is a random UUID (version 4).
+	Generator func() string
+}
+
+// RequestID is a middleware that propagates the X-Request-ID header, generating
+// a UUID when it is absent. The ID is stored in the request context and set on
+// the response header.
+func RequestID(next http.Handler) http.Handler {
+	return RequestIDWithConfig(RequestIDConfig{})(next)
+}
+
+// RequestIDWithConfig creates a request ID middleware with custom configuration
+func RequestIDWithConfig(config RequestIDConfig) func(http.Handler) http.Handler {
+	if config.Header == "" {
+		config.Header = "X-Request-ID"
+	}
+	if config.Generator == nil {
+		config.Generator = newUUID
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(config.Header)
+			if !validRequestID(id) {
+				id = config.Generator()
+			}
+
+			w.Header().Set(config.Header, id)
+			next.ServeHTTP(w, WithValue(r, requestIDKey, id))
+		})
+	}
+}
+
+// GetRequestID returns the request ID stored by the RequestID middleware, or ""
+func GetRequestID(r *http.Request) string {
+	id, _ := Value(r, requestIDKey)
+	return id
+}
+
+// validRequestID reports whether an incoming ID is safe to reuse. Overlong
+// values and control characters are rejected so clients can't inject into logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40 // Version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -3,34 +3,117 @@ package middleware
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 )
 
-// ResponseWriterWrapper wraps http.ResponseWriter to capture the status code
-// while preserving interfaces like http.Hijacker for WebSocket upgrades.
-type ResponseWriterWrapper struct {
+// ResponseWriter wraps http.ResponseWriter to capture the status code and body
+// size while forwarding http.Flusher, http.Hijacker, http.Pusher and
+// io.ReaderFrom, so streaming responses, WebSocket upgrades and sendfile keep
+// working behind it. Create it with NewResponseWriter.
+type ResponseWriter struct {
 	http.ResponseWriter
-	StatusCode int
+	StatusCode   int
+	BytesWritten int
+	wroteHeader  bool
 }
 
-// WriteHeader captures the status code.
-func (rw *ResponseWriterWrapper) WriteHeader(code int) {
-	rw.StatusCode = code
+// ResponseWriterWrapper is the former name of ResponseWriter.
+//
+// Deprecated: Use ResponseWriter and NewResponseWriter.
+type ResponseWriterWrapper = ResponseWriter
+
+// NewResponseWriter wraps w so its status and size can be read once the next
+// handler returns. If w is already a *ResponseWriter, e.g. because Logger runs
+// further out, it is returned as is rather than wrapped again, so stacked
+// middleware share one wrapper.
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	if rw, ok := w.(*ResponseWriter); ok {
+		return rw
+	}
+	return &ResponseWriter{ResponseWriter: w, StatusCode: http.StatusOK}
+}
+
+// Written reports whether the status has been sent, explicitly or by writing
+// the body
+func (rw *ResponseWriter) Written() bool {
+	return rw.wroteHeader
+}
+
+// WriteHeader captures the status code. Only the first call counts, matching
+// what net/http actually sends.
+func (rw *ResponseWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.StatusCode = code
+		rw.wroteHeader = true
+	}
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Write counts the bytes of the response body. Writing before WriteHeader
+// sends an implicit 200, which is recorded as the status.
+func (rw *ResponseWriter) Write(b []byte) (int, error) {
+	rw.implicitHeader()
+	n, err := rw.ResponseWriter.Write(b)
+	rw.BytesWritten += n
+	return n, err
+}
+
+// ReadFrom implements io.ReaderFrom, letting net/http use sendfile when the
+// underlying ResponseWriter supports it, and counts the bytes copied.
+func (rw *ResponseWriter) ReadFrom(src io.Reader) (int64, error) {
+	rw.implicitHeader()
+	if rf, ok := rw.ResponseWriter.(io.ReaderFrom); ok {
+		n, err := rf.ReadFrom(src)
+		rw.BytesWritten += int(n)
+		return n, err
+	}
+	// Hide ReadFrom from io.Copy so it doesn't call back into this method
+	return io.Copy(writerOnly{rw}, src)
+}
+
+// implicitHeader records the 200 that net/http sends when the body is written
+// before any status
+func (rw *ResponseWriter) implicitHeader() {
+	if !rw.wroteHeader {
+		rw.StatusCode = http.StatusOK
+		rw.wroteHeader = true
+	}
+}
+
 // Hijack implements http.Hijacker by delegating to the underlying ResponseWriter.
-func (rw *ResponseWriterWrapper) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+func (rw *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	if hj, ok := rw.ResponseWriter.(http.Hijacker); ok {
 		return hj.Hijack()
 	}
-	return nil, nil, fmt.Errorf("underlying ResponseWriter does not implement http.Hijacker")
+	return nil, nil, fmt.Errorf("underlying ResponseWriter does not implement http.Hijacker: %w", http.ErrNotSupported)
 }
 
 // Flush implements http.Flusher by delegating to the underlying ResponseWriter.
-func (rw *ResponseWriterWrapper) Flush() {
+// Flushing sends the headers, so an unset status is recorded as 200.
+func (rw *ResponseWriter) Flush() {
 	if fl, ok := rw.ResponseWriter.(http.Flusher); ok {
+		rw.implicitHeader()
 		fl.Flush()
 	}
 }
+
+// Push implements http.Pusher by delegating to the underlying ResponseWriter.
+func (rw *ResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := rw.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the underlying ResponseWriter so http.ResponseController can
+// reach it.
+func (rw *ResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// writerOnly hides every method of a writer except Write
+type writerOnly struct {
+	io.Writer
+}
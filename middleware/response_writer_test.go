@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jtclarkjr/router-go/routertest"
+)
+
+// quietLogger is Logger writing nowhere
+var quietLogger = LoggerWithConfig(LoggerConfig{Output: io.Discard})
+
+func TestLoggerServerSentEvents(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(quietLogger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: first\n\n"))
+		w.(http.Flusher).Flush()
+
+		// Hold the response open, so the event only arrives if it was flushed
+		<-release
+	})))
+	defer srv.Close()
+	defer close(release)
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	lines := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(resp.Body).ReadString('\n')
+		lines <- line
+	}()
+	select {
+	case line := <-lines:
+		if line != "data: first\n" {
+			t.Errorf("first line = %q, want %q", line, "data: first\n")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event not flushed through Logger")
+	}
+}
+
+func TestLoggerHijack(t *testing.T) {
+	srv := httptest.NewServer(quietLogger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("Hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		buf.Flush()
+	})))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); string(body) != "hijacked" {
+		t.Errorf("body = %q, want %q", body, "hijacked")
+	}
+}
+
+func TestResponseWriterUnsupported(t *testing.T) {
+	rw := NewResponseWriter(httptest.NewRecorder())
+
+	if _, _, err := rw.Hijack(); !errors.Is(err, http.ErrNotSupported) || !strings.Contains(err.Error(), "Hijacker") {
+		t.Errorf("Hijack error = %v, want http.ErrNotSupported naming http.Hijacker", err)
+	}
+	if err := rw.Push("/app.js", nil); err != http.ErrNotSupported {
+		t.Errorf("Push error = %v, want http.ErrNotSupported", err)
+	}
+}
+
+func TestWebSocketWithoutHijacker(t *testing.T) {
+	// Wrapped by Logger, the recorder still reports hijacking as unsupported
+	h := quietLogger(WebSocket(func(conn net.Conn, r *http.Request) {
+		t.Error("upgraded without a hijackable connection")
+	})(http.NotFoundHandler()))
+
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	rec := routertest.Do(h, req)
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "does not support hijacking") {
+		t.Errorf("status = %d, body = %q, want the unsupported hijacking error", rec.Code, rec.Body.String())
+	}
+}
+
+func TestResponseWriterStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(w http.ResponseWriter)
+		status  int
+		bytes   int
+	}{
+		{"explicit", func(w http.ResponseWriter) { w.WriteHeader(http.StatusCreated); w.Write([]byte("ok")) }, http.StatusCreated, 2},
+		{"implicit", func(w http.ResponseWriter) { w.Write([]byte("hello")) }, http.StatusOK, 5},
+		{"first wins", func(w http.ResponseWriter) { w.WriteHeader(http.StatusNotFound); w.WriteHeader(http.StatusOK) }, http.StatusNotFound, 0},
+		{"flush", func(w http.ResponseWriter) { w.(http.Flusher).Flush(); w.WriteHeader(http.StatusTeapot) }, http.StatusOK, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := NewResponseWriter(httptest.NewRecorder())
+			tt.handler(rw)
+			if rw.StatusCode != tt.status || rw.BytesWritten != tt.bytes {
+				t.Errorf("StatusCode, BytesWritten = %d, %d, want %d, %d", rw.StatusCode, rw.BytesWritten, tt.status, tt.bytes)
+			}
+			if NewResponseWriter(rw) != rw {
+				t.Error("NewResponseWriter wrapped a *ResponseWriter again")
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// StripPrefix removes prefix from the request path before the next handler
+// sees it, like http.StripPrefix, so a router behind a proxy that doesn't
+// strip its own mount path can be written without it. The prefix only matches
+// whole segments, both Path and RawPath are updated, and the prefix itself is
+// served as "/". Requests outside the prefix get a 404. Matching happens
+// before route middleware runs, so wrap the router:
+//
+//	http.ListenAndServe(":8080", middleware.StripPrefix("/service-a")(r))
+func StripPrefix(prefix string) func(http.Handler) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path, ok := stripPathPrefix(r.URL.Path, prefix)
+			rawPath := r.URL.RawPath
+			if ok && rawPath != "" {
+				rawPath, ok = stripPathPrefix(rawPath, prefix)
+			}
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			next.ServeHTTP(w, withPath(r, path, rawPath))
+		})
+	}
+}
+
+// RewritePath replaces the request path with the result of rewrite before the
+// next handler sees it, so routes are matched against the rewritten path.
+// rewrite is given the decoded path, and RawPath is cleared, so encoded
+// slashes in the original path are not preserved. Like StripPrefix, it wraps
+// the router rather than its routes.
+func RewritePath(rewrite func(path string) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := rewrite(r.URL.Path)
+			if path == r.URL.Path {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !strings.HasPrefix(path, "/") {
+				path = "/" + path
+			}
+			next.ServeHTTP(w, withPath(r, path, ""))
+		})
+	}
+}
+
+// stripPathPrefix removes prefix from path if it ends at a segment boundary,
+// leaving "/" when nothing remains
+func stripPathPrefix(path, prefix string) (string, bool) {
+	rest, ok := strings.CutPrefix(path, prefix)
+	if !ok || rest != "" && !strings.HasPrefix(rest, "/") {
+		return "", false
+	}
+	if rest == "" {
+		rest = "/"
+	}
+	return rest, true
+}
+
+// withPath returns a shallow copy of r with its URL path replaced
+func withPath(r *http.Request, path, rawPath string) *http.Request {
+	rewritten := new(http.Request)
+	*rewritten = *r
+	rewritten.URL = new(url.URL)
+	*rewritten.URL = *r.URL
+	rewritten.URL.Path = path
+	rewritten.URL.RawPath = rawPath
+	return rewritten
+}
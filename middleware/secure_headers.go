@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+)
+
+// SecureHeadersConfig defines the hardening headers set by the SecureHeaders
+// middleware. An empty field omits its header.
+type SecureHeadersConfig struct {
+	// StrictTransportSecurity is the Strict-Transport-Security value. Browsers
+	// ignore it over plain HTTP, so it is safe to set behind a TLS proxy.
+	StrictTransportSecurity string
+
+	// ContentTypeNosniff sets X-Content-Type-Options: nosniff
+	ContentTypeNosniff bool
+
+	// XFrameOptions is the X-Frame-Options value, e.g. "DENY" or "SAMEORIGIN"
+	XFrameOptions string
+
+	// ReferrerPolicy is the Referrer-Policy value
+	ReferrerPolicy string
+
+	// ContentSecurityPolicy is the Content-Security-Policy value
+	ContentSecurityPolicy string
+}
+
+// DefaultSecureHeadersConfig returns a strict default configuration: HSTS for
+// two years including subdomains, nosniff, no framing, origin-only referrers
+// for cross-origin requests, and a policy that only loads same-origin content
+func DefaultSecureHeadersConfig() SecureHeadersConfig {
+	return SecureHeadersConfig{
+		StrictTransportSecurity: "max-age=63072000; includeSubDomains",
+		ContentTypeNosniff:      true,
+		XFrameOptions:           "DENY",
+		ReferrerPolicy:          "strict-origin-when-cross-origin",
+		ContentSecurityPolicy:   "default-src 'self'; frame-ancestors 'none'",
+	}
+}
+
+// SecureHeaders sets common hardening headers on every response. Start from
+// DefaultSecureHeadersConfig and clear the fields you don't want.
+func SecureHeaders(config SecureHeadersConfig) func(http.Handler) http.Handler {
+	headers := make(http.Header)
+	if config.StrictTransportSecurity != "" {
+		headers.Set("Strict-Transport-Security", config.StrictTransportSecurity)
+	}
+	if config.ContentTypeNosniff {
+		headers.Set("X-Content-Type-Options", "nosniff")
+	}
+	if config.XFrameOptions != "" {
+		headers.Set("X-Frame-Options", config.XFrameOptions)
+	}
+	if config.ReferrerPolicy != "" {
+		headers.Set("Referrer-Policy", config.ReferrerPolicy)
+	}
+	if config.ContentSecurityPolicy != "" {
+		headers.Set("Content-Security-Policy", config.ContentSecurityPolicy)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Each response gets its own slices, so adding to a header can't
+			// write into the shared ones
+			dst := w.Header()
+			for key, values := range headers {
+				dst[key] = slices.Clone(values)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
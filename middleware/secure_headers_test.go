@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/jtclarkjr/router-go/routertest"
+)
+
+func TestSecureHeadersNotShared(t *testing.T) {
+	h := SecureHeaders(SecureHeadersConfig{XFrameOptions: "DENY"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/edit" {
+			w.Header()["X-Frame-Options"][0] = "SAMEORIGIN"
+		}
+	}))
+
+	routertest.Get(h, "/edit")
+	rec := routertest.Get(h, "/")
+	if got := rec.Header().Values("X-Frame-Options"); !slices.Equal(got, []string{"DENY"}) {
+		t.Errorf("X-Frame-Options = %q after another response changed it, want only DENY", got)
+	}
+}
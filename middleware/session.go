@@ -0,0 +1,343 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"log"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionKey stores the session
+var sessionKey = NewKey[*SessionData]("session")
+
+// SessionStore persists session values by session ID. Implementations must be
+// safe for concurrent use.
+type SessionStore interface {
+	// Get returns the values of a session, or nil if it doesn't exist or expired
+	Get(ctx context.Context, id string) (map[string]any, error)
+
+	// Save stores the values of a session for ttl
+	Save(ctx context.Context, id string, values map[string]any, ttl time.Duration) error
+
+	// Delete removes a session
+	Delete(ctx context.Context, id string) error
+}
+
+// SessionConfig defines the configuration for the session middleware
+type SessionConfig struct {
+	// Keys sign the session cookie. The first key signs new cookies, and all
+	// keys are accepted when verifying, so keys can be rotated by prepending a
+	// new one. Required.
+	Keys [][]byte
+
+	// CookieName is the cookie holding the session ID.
+	// Default value is "session".
+	CookieName string
+
+	// CookiePath is the Path attribute of the cookie. Default value is "/".
+	CookiePath string
+
+	// CookieDomain is the Domain attribute of the cookie. Default value is "".
+	CookieDomain string
+
+	// CookieSecure marks the cookie Secure. It is always set on TLS requests.
+	CookieSecure bool
+
+	// SameSite is the SameSite attribute of the cookie.
+	// Default value is http.SameSiteLaxMode.
+	SameSite http.SameSite
+
+	// MaxAge is how long a session lives after it was last saved.
+	// Default value is 24 hours.
+	MaxAge time.Duration
+}
+
+// SessionData holds the values of the current session. It is safe for
+// concurrent use.
+type SessionData struct {
+	mu        sync.Mutex
+	id        string
+	values    map[string]any
+	changed   bool
+	renewed   bool
+	destroyed bool
+}
+
+// Get returns the value stored under key, or nil
+func (s *SessionData) Get(key string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+// Set stores a value under key
+func (s *SessionData) Set(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	s.changed = true
+}
+
+// Delete removes the value stored under key
+func (s *SessionData) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	s.changed = true
+}
+
+// Renew moves the session to a new ID, keeping its values. Call it when a
+// user logs in to prevent session fixation.
+func (s *SessionData) Renew() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.renewed = true
+	s.changed = true
+}
+
+// Destroy removes the session from the store and expires its cookie
+func (s *SessionData) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = make(map[string]any)
+	s.destroyed = true
+}
+
+// Session loads the session named by the request's signed cookie, or starts an
+// empty one, and makes it available through GetSession. Changes are saved to
+// the store, and the cookie set, just before the response is written. Sessions
+// that are never modified aren't stored, so anonymous visitors cost nothing.
+func Session(store SessionStore, config SessionConfig) func(http.Handler) http.Handler {
+	if len(config.Keys) == 0 {
+		panic("middleware: SessionConfig.Keys is required")
+	}
+	if config.CookieName == "" {
+		config.CookieName = "session"
+	}
+	if config.CookiePath == "" {
+		config.CookiePath = "/"
+	}
+	if config.SameSite == 0 {
+		config.SameSite = http.SameSiteLaxMode
+	}
+	if config.MaxAge <= 0 {
+		config.MaxAge = 24 * time.Hour
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session := &SessionData{values: make(map[string]any)}
+			if c, err := r.Cookie(config.CookieName); err == nil {
+				if id, ok := verifySessionCookie(c.Value, config.Keys); ok {
+					values, err := store.Get(r.Context(), id)
+					if err != nil {
+						log.Printf("[Session] loading session: %v", err)
+					}
+					if values != nil {
+						session.id = id
+						session.values = values
+					}
+				}
+			}
+
+			sw := &sessionWriter{ResponseWriter: w, save: func() {
+				saveSession(w, r, store, session, config)
+			}}
+			next.ServeHTTP(sw, WithValue(r, sessionKey, session))
+
+			// Save sessions of handlers that didn't write a response
+			sw.commit()
+		})
+	}
+}
+
+// GetSession returns the session loaded by the Session middleware, or nil
+func GetSession(r *http.Request) *SessionData {
+	session, _ := Value(r, sessionKey)
+	return session
+}
+
+// saveSession persists a changed session and sets or expires its cookie
+func saveSession(w http.ResponseWriter, r *http.Request, store SessionStore, session *SessionData, config SessionConfig) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	cookie := &http.Cookie{
+		Name:     config.CookieName,
+		Path:     config.CookiePath,
+		Domain:   config.CookieDomain,
+		Secure:   config.CookieSecure || r.TLS != nil,
+		HttpOnly: true,
+		SameSite: config.SameSite,
+	}
+
+	if session.destroyed {
+		if session.id != "" {
+			if err := store.Delete(r.Context(), session.id); err != nil {
+				log.Printf("[Session] deleting session: %v", err)
+			}
+		}
+		cookie.MaxAge = -1
+		http.SetCookie(w, cookie)
+		return
+	}
+	if !session.changed {
+		return
+	}
+
+	if session.renewed && session.id != "" {
+		if err := store.Delete(r.Context(), session.id); err != nil {
+			log.Printf("[Session] deleting session: %v", err)
+		}
+		session.id = ""
+	}
+	if session.id == "" {
+		session.id = newSessionID()
+	}
+	if err := store.Save(r.Context(), session.id, session.values, config.MaxAge); err != nil {
+		log.Printf("[Session] saving session: %v", err)
+		return
+	}
+
+	cookie.Value = signSessionID(session.id, config.Keys[0])
+	cookie.MaxAge = int(config.MaxAge.Seconds())
+	http.SetCookie(w, cookie)
+}
+
+// newSessionID returns a random session ID
+func newSessionID() string {
+	var b [32]byte
+	_, _ = rand.Read(b[:])
+	return base64.RawURLEncoding.EncodeToString(b[:])
+}
+
+// signSessionID returns the cookie value for id: the ID and its HMAC
+func signSessionID(id string, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id))
+	return id + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifySessionCookie returns the session ID of a cookie signed by any key
+func verifySessionCookie(value string, keys [][]byte) (string, bool) {
+	id, _, ok := strings.Cut(value, ".")
+	if !ok {
+		return "", false
+	}
+	for _, key := range keys {
+		if hmac.Equal([]byte(signSessionID(id, key)), []byte(value)) {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// sessionWriter saves the session the first time the response is written,
+// while headers can still be set
+type sessionWriter struct {
+	http.ResponseWriter
+	save      func()
+	committed bool
+}
+
+// commit saves the session once
+func (sw *sessionWriter) commit() {
+	if !sw.committed {
+		sw.committed = true
+		sw.save()
+	}
+}
+
+// WriteHeader saves the session before sending the status
+func (sw *sessionWriter) WriteHeader(code int) {
+	sw.commit()
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+// Write saves the session before sending the body
+func (sw *sessionWriter) Write(b []byte) (int, error) {
+	sw.commit()
+	return sw.ResponseWriter.Write(b)
+}
+
+// Flush saves the session before flushing
+func (sw *sessionWriter) Flush() {
+	sw.commit()
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController
+func (sw *sessionWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// MemoryStore is a SessionStore that keeps sessions in memory. Sessions are
+// lost on restart and aren't shared between instances.
+type MemoryStore struct {
+	mu          sync.Mutex
+	sessions    map[string]memorySession
+	lastCleanup time.Time
+}
+
+// memorySession is a stored session and its expiry
+type memorySession struct {
+	values  map[string]any
+	expires time.Time
+}
+
+// NewMemoryStore creates an empty in-memory session store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]memorySession), lastCleanup: time.Now()}
+}
+
+// Get implements the SessionStore interface
+func (m *MemoryStore) Get(_ context.Context, id string) (map[string]any, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(s.expires) {
+		delete(m.sessions, id)
+		return nil, nil
+	}
+	return maps.Clone(s.values), nil
+}
+
+// Save implements the SessionStore interface. Expired sessions are evicted
+// once a minute while saving, so the store doesn't need a background goroutine.
+func (m *MemoryStore) Save(_ context.Context, id string, values map[string]any, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.lastCleanup) >= time.Minute {
+		for key, s := range m.sessions {
+			if now.After(s.expires) {
+				delete(m.sessions, key)
+			}
+		}
+		m.lastCleanup = now
+	}
+	m.sessions[id] = memorySession{values: maps.Clone(values), expires: now.Add(ttl)}
+	return nil
+}
+
+// Delete implements the SessionStore interface
+func (m *MemoryStore) Delete(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// StripSlashes removes trailing slashes from the request path, so /users/ is
+// served as /users. The root path is left alone. Matching happens before
+// route middleware runs, so wrap the router, or a router mounted as a group:
+//
+//	r.Mount("/api", middleware.StripSlashes(apiRouter))
+func StripSlashes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := trimTrailingSlashes(r.URL.Path)
+		if path == r.URL.Path {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		stripped := new(http.Request)
+		*stripped = *r
+		stripped.URL = new(url.URL)
+		*stripped.URL = *r.URL
+		stripped.URL.Path = path
+		if r.URL.RawPath != "" {
+			stripped.URL.RawPath = trimTrailingSlashes(r.URL.RawPath)
+		}
+		next.ServeHTTP(w, stripped)
+	})
+}
+
+// RedirectSlashes redirects paths with trailing slashes to the path without
+// them, with a 301 for GET and HEAD and a 308 for other methods so the body
+// is resent. Like StripSlashes, it wraps the router rather than its routes.
+func RedirectSlashes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := trimTrailingSlashes(r.URL.Path)
+		if path == r.URL.Path {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Collapse leading slashes so "//evil.com/" can't become a
+		// protocol-relative redirect to another host
+		target := url.URL{Path: "/" + strings.TrimLeft(path, "/"), RawQuery: r.URL.RawQuery}
+		if r.URL.RawPath != "" {
+			target.RawPath = "/" + strings.TrimLeft(trimTrailingSlashes(r.URL.RawPath), "/")
+		}
+
+		code := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			code = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, target.String(), code)
+	})
+}
+
+// trimTrailingSlashes removes trailing slashes, keeping the root path
+func trimTrailingSlashes(path string) string {
+	if len(path) <= 1 {
+		return path
+	}
+	trimmed := strings.TrimRight(path, "/")
+	if trimmed == "" {
+		return "/"
+	}
+	return trimmed
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jtclarkjr/router-go/internal/routectx"
+)
+
+// StructuredLogger creates a logging middleware that emits one structured
+// record per request through logger, for production log pipelines that expect
+// JSON or key/value output rather than colored lines. Each record has the
+// method, path, matched route pattern, status, bytes written, duration, remote
+// IP and, when present, the request ID.
+func StructuredLogger(logger *slog.Logger) func(http.Handler) http.Handler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			wrappedWriter := NewResponseWriter(w)
+
+			r = withErrorSlot(r)
+			next.ServeHTTP(wrappedWriter, r)
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", routectx.Pattern(r.Context())),
+				slog.Int("status", wrappedWriter.StatusCode),
+				slog.Int("bytes", wrappedWriter.BytesWritten),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("remote_ip", remoteIP(r)),
+			}
+			if requestID := GetRequestID(r); requestID != "" {
+				attrs = append(attrs, slog.String("request_id", requestID))
+			}
+			if err := ErrorFromContext(r); err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+			}
+
+			level := slog.LevelInfo
+			if wrappedWriter.StatusCode >= 500 {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(r.Context(), level, "request", attrs...)
+		})
+	}
+}
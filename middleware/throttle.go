@@ -1,24 +1,132 @@
 package middleware
 
 import (
+	"math"
 	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
 )
 
-// Throttle limits the number of concurrent requests.
+// ThrottleConfig defines the configuration for the throttle middleware
+type ThrottleConfig struct {
+	// Limit is the number of requests processed at the same time
+	Limit int
+
+	// BacklogLimit is the number of requests that may wait for a slot once
+	// Limit is reached. Requests beyond it are rejected immediately.
+	// Default value is 0 (no waiting).
+	BacklogLimit int
+
+	// BacklogTimeout is how long a request waits for a slot before it is
+	// rejected. Default value is 0 (wait until the request is cancelled).
+	BacklogTimeout time.Duration
+}
+
+// Throttle limits the number of concurrent requests. A request cancelled while
+// waiting for a slot returns without calling the next handler.
 func Throttle(limit int) func(http.Handler) http.Handler {
-	sem := make(chan struct{}, limit)
+	return (&Throttler{sem: make(chan struct{}, limit)}).Handler
+}
+
+// ThrottleWithConfig limits the number of concurrent requests with a bounded
+// backlog. Requests that find the backlog full, or that wait longer than
+// BacklogTimeout, get a 503 with Retry-After. A request cancelled while
+// waiting returns without being processed.
+func ThrottleWithConfig(config ThrottleConfig) func(http.Handler) http.Handler {
+	return NewThrottler(config).Handler
+}
+
+// Throttler limits the number of concurrent requests like ThrottleWithConfig,
+// and reports how busy it is. Every handler it wraps shares the same slots.
+//
+//	throttler := middleware.NewThrottler(middleware.ThrottleConfig{Limit: 10, BacklogLimit: 50})
+//	r.Use(throttler.Handler)
+//	inFlight, waiting := throttler.Stats()
+type Throttler struct {
+	sem chan struct{}
+
+	// backlog holds a place for every request being processed or waiting.
+	// It is nil for Throttle, whose requests wait without bound.
+	backlog chan struct{}
+
+	timeout    time.Duration
+	retryAfter string
+	waiting    atomic.Int64
+}
 
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Acquire a token
-			sem <- struct{}{}
+// NewThrottler creates a Throttler with the given configuration. It panics if
+// Limit is less than 1 or BacklogLimit is negative.
+func NewThrottler(config ThrottleConfig) *Throttler {
+	if config.Limit < 1 {
+		panic("middleware: Throttle limit must be at least 1")
+	}
+	if config.BacklogLimit < 0 {
+		panic("middleware: Throttle backlog limit must not be negative")
+	}
+
+	return &Throttler{
+		sem:        make(chan struct{}, config.Limit),
+		backlog:    make(chan struct{}, config.Limit+config.BacklogLimit),
+		timeout:    config.BacklogTimeout,
+		retryAfter: strconv.Itoa(int(math.Max(1, math.Ceil(config.BacklogTimeout.Seconds())))),
+	}
+}
+
+// Stats returns the number of requests being processed and the number waiting
+// for a slot. They are read separately, so under load they are approximate.
+func (t *Throttler) Stats() (inFlight, waiting int) {
+	return len(t.sem), int(t.waiting.Load())
+}
+
+// Handler is the throttling middleware
+func (t *Throttler) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Take a place in line, or reject straight away if the line is full
+		if t.backlog != nil {
+			select {
+			case t.backlog <- struct{}{}:
+			default:
+				w.Header().Set("Retry-After", t.retryAfter)
+				http.Error(w, "Server is busy", http.StatusServiceUnavailable)
+				return
+			}
 			defer func() {
-				// Release the token
-				<-sem
+				<-t.backlog
 			}()
+		}
 
-			// Call the next handler
-			next.ServeHTTP(w, r)
-		})
-	}
+		var timeout <-chan time.Time
+		if t.timeout > 0 {
+			timer := time.NewTimer(t.timeout)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+
+		// Wait for a slot, unless the client gives up first
+		acquired := false
+		select {
+		case t.sem <- struct{}{}:
+			acquired = true
+		default:
+			t.waiting.Add(1)
+			select {
+			case t.sem <- struct{}{}:
+				acquired = true
+			case <-timeout:
+				w.Header().Set("Retry-After", t.retryAfter)
+				http.Error(w, "Timed out waiting for a free slot", http.StatusServiceUnavailable)
+			case <-r.Context().Done():
+			}
+			t.waiting.Add(-1)
+		}
+		if !acquired {
+			return
+		}
+		defer func() {
+			<-t.sem
+		}()
+
+		next.ServeHTTP(w, r)
+	})
 }
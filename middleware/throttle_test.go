@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jtclarkjr/router-go/routertest"
+)
+
+// blockingHandler signals started when a request arrives and holds it until
+// release is closed
+func blockingHandler(started chan<- struct{}, release <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/block" {
+			started <- struct{}{}
+			<-release
+		}
+	})
+}
+
+func TestThrottleCancelledRequestFreesNoSlot(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	h := Throttle(1)(blockingHandler(started, release))
+
+	// Occupy the only slot
+	done := make(chan struct{})
+	go func() {
+		routertest.Get(h, "/block")
+		close(done)
+	}()
+	<-started
+
+	// A request whose client has gone away returns without being served
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	served := make(chan struct{})
+	go func() {
+		routertest.Do(h, req)
+		close(served)
+	}()
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Fatal("cancelled request kept waiting for a slot")
+	}
+
+	// Once the slot is released, the next request gets it straight away
+	close(release)
+	<-done
+	next := make(chan int, 1)
+	go func() { next <- routertest.Get(h, "/").Code }()
+	select {
+	case code := <-next:
+		if code != http.StatusOK {
+			t.Errorf("status = %d, want 200", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("slot still held after the cancelled request returned")
+	}
+}
+
+func TestThrottlerCancelWhileWaiting(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	throttler := NewThrottler(ThrottleConfig{Limit: 1, BacklogLimit: 1})
+	h := throttler.Handler(blockingHandler(started, release))
+
+	go routertest.Get(h, "/block")
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	served := make(chan struct{})
+	go func() {
+		routertest.Do(h, req)
+		close(served)
+	}()
+
+	// Wait until the request is queued, then cancel it
+	for _, waiting := throttler.Stats(); waiting != 1; _, waiting = throttler.Stats() {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-served
+
+	if inFlight, waiting := throttler.Stats(); inFlight != 1 || waiting != 0 {
+		t.Errorf("Stats() = %d, %d after cancelling, want 1, 0", inFlight, waiting)
+	}
+
+	// The cancelled request also gave up its place in the backlog
+	close(release)
+	for inFlight, _ := throttler.Stats(); inFlight != 0; inFlight, _ = throttler.Stats() {
+		time.Sleep(time.Millisecond)
+	}
+	if code := routertest.Get(h, "/").Code; code != http.StatusOK {
+		t.Errorf("status = %d, want 200", code)
+	}
+}
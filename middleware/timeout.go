@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TimeoutConfig defines the configuration for the timeout middleware
+type TimeoutConfig struct {
+	// Timeout is how long the handler may run
+	Timeout time.Duration
+
+	// StatusCode is sent when the handler runs out of time.
+	// Default value is 503 Service Unavailable.
+	StatusCode int
+
+	// Body is sent when the handler runs out of time.
+	// Default value is the status text of StatusCode.
+	Body string
+}
+
+// Timeout cancels the request context after d and responds with a 503 if the
+// handler hasn't finished by then. Used with With it gives a single route its
+// own budget; nested inside a router-wide Timeout, the shorter of the two
+// applies, so a route can tighten the global timeout but not extend it:
+//
+//	r.Use(middleware.Timeout(10 * time.Second))
+//	r.With(middleware.Timeout(2 * time.Second)).Get("/search", search)
+func Timeout(d time.Duration) func(http.Handler) http.Handler {
+	return TimeoutWithConfig(TimeoutConfig{Timeout: d})
+}
+
+// TimeRemaining returns how long the request has left before its context
+// deadline, such as the one set by Timeout, or false if it has none. It is
+// never negative, so handlers can compare it against the expected cost of
+// their work and give up early.
+func TimeRemaining(r *http.Request) (time.Duration, bool) {
+	deadline, ok := r.Context().Deadline()
+	if !ok {
+		return 0, false
+	}
+	return max(0, time.Until(deadline)), true
+}
+
+// TimeoutWithConfig creates a timeout middleware with custom configuration.
+//
+// Like http.TimeoutHandler, the handler runs in its own goroutine and writes
+// into a buffer that is only copied to the client if it finishes in time.
+// Writes made after the timeout response fail with http.ErrHandlerTimeout
+// instead of reaching the client, so there is no write race. As a consequence,
+// handlers behind Timeout can't stream, flush or hijack the connection.
+// A handler that returns at the deadline without having written anything,
+// typically because it stopped on the cancelled context, gets the timeout
+// response too.
+// WebSocket upgrade requests are passed through without a timeout.
+func TimeoutWithConfig(config TimeoutConfig) func(http.Handler) http.Handler {
+	if config.StatusCode == 0 {
+		config.StatusCode = http.StatusServiceUnavailable
+	}
+	if config.Body == "" {
+		config.Body = http.StatusText(config.StatusCode)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// A WebSocket connection outlives any request timeout
+			if IsWebSocketUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), config.Timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			tw := &timeoutWriter{header: make(http.Header), statusCode: http.StatusOK}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+
+			go func() {
+				defer func() {
+					if err := recover(); err != nil {
+						panicked <- err
+					}
+				}()
+				next.ServeHTTP(tw, r)
+				close(done)
+			}()
+
+			select {
+			case err := <-panicked:
+				// Re-panic on the serving goroutine so Recoverer can handle it
+				panic(err)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				// A handler that gave up on the deadline without responding
+				// gets the timeout response, whichever case was selected
+				if ctx.Err() == context.DeadlineExceeded && !tw.wroteHeader {
+					tw.timedOut = true
+					http.Error(w, config.Body, config.StatusCode)
+					return
+				}
+				tw.copyTo(w)
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				// The handler may have finished just as the deadline passed,
+				// in which case a response it wrote wins
+				select {
+				case <-done:
+					if tw.wroteHeader {
+						tw.copyTo(w)
+						return
+					}
+				default:
+				}
+				tw.timedOut = true
+				// A cancelled client gets nothing; a real timeout gets the error response
+				if ctx.Err() == context.DeadlineExceeded {
+					http.Error(w, config.Body, config.StatusCode)
+				}
+			}
+		})
+	}
+}
+
+// timeoutWriter buffers a response until the handler finishes or times out
+type timeoutWriter struct {
+	mu          sync.Mutex
+	header      http.Header
+	body        bytes.Buffer
+	statusCode  int
+	wroteHeader bool
+	timedOut    bool
+}
+
+// copyTo writes the buffered response to w. The caller holds tw.mu.
+func (tw *timeoutWriter) copyTo(w http.ResponseWriter) {
+	dst := w.Header()
+	for key, values := range tw.header {
+		dst[key] = values
+	}
+	w.WriteHeader(tw.statusCode)
+	_, _ = w.Write(tw.body.Bytes())
+}
+
+// Header returns the buffered header map
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// Write buffers the body, or fails once the timeout response has been sent
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.wroteHeader = true
+	return tw.body.Write(b)
+}
+
+// WriteHeader records the first status code written before the timeout
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.statusCode = code
+	tw.wroteHeader = true
+}
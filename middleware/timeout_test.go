@@ -0,0 +1,75 @@
+package middleware_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	router "github.com/jtclarkjr/router-go"
+	"github.com/jtclarkjr/router-go/middleware"
+	"github.com/jtclarkjr/router-go/routertest"
+)
+
+func TestTimeoutPerRoute(t *testing.T) {
+	remaining := make(chan time.Duration, 1)
+	lateWrite := make(chan error, 1)
+
+	r := router.NewRouter()
+	r.Use(middleware.Timeout(time.Second))
+	r.Get("/fast", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Fast", "yes")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("done"))
+	})
+	r.With(middleware.Timeout(20*time.Millisecond)).Get("/slow", func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+	})
+	r.With(middleware.Timeout(20*time.Millisecond)).Get("/late", func(w http.ResponseWriter, req *http.Request) {
+		<-req.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+		_, err := w.Write([]byte("too late"))
+		lateWrite <- err
+	})
+	// A route can tighten the global budget but not extend it
+	r.With(middleware.Timeout(time.Hour)).Get("/remaining", func(w http.ResponseWriter, req *http.Request) {
+		left, _ := middleware.TimeRemaining(req)
+		remaining <- left
+	})
+
+	rec := routertest.Get(r, "/fast")
+	if rec.Code != http.StatusCreated || rec.Body.String() != "done" || rec.Header().Get("X-Fast") != "yes" {
+		t.Errorf("GET /fast: status = %d, body = %q, headers = %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+
+	start := time.Now()
+	rec = routertest.Get(r, "/slow")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /slow: status = %d, want 503", rec.Code)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("GET /slow took %v, want the route's 20ms budget", elapsed)
+	}
+
+	rec = routertest.Get(r, "/late")
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() == "too late" {
+		t.Errorf("GET /late: status = %d, body = %q, want the timeout response", rec.Code, rec.Body.String())
+	}
+	if err := <-lateWrite; !errors.Is(err, http.ErrHandlerTimeout) {
+		t.Errorf("late write error = %v, want http.ErrHandlerTimeout", err)
+	}
+
+	routertest.Get(r, "/remaining")
+	if left := <-remaining; left <= 0 || left > time.Second {
+		t.Errorf("TimeRemaining = %v, want at most the global second", left)
+	}
+}
+
+func TestTimeRemainingWithoutDeadline(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := middleware.TimeRemaining(r); ok {
+			t.Error("TimeRemaining reported a deadline for a request without one")
+		}
+	})
+	routertest.Get(h, "/")
+}
@@ -0,0 +1,18 @@
+module github.com/jtclarkjr/router-go/middleware/tracing
+
+go 1.24.1
+
+require (
+	github.com/jtclarkjr/router-go v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/otel v1.35.0
+	go.opentelemetry.io/otel/trace v1.35.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.2 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel/metric v1.35.0 // indirect
+)
+
+replace github.com/jtclarkjr/router-go => ../..
@@ -0,0 +1,88 @@
+// Package tracing provides a middleware that traces requests with
+// OpenTelemetry. It lives in its own package so routers that don't use it
+// don't depend on the OpenTelemetry SDK.
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	router "github.com/jtclarkjr/router-go"
+	"github.com/jtclarkjr/router-go/middleware"
+)
+
+// tracerName identifies the spans created by this package
+const tracerName = "github.com/jtclarkjr/router-go/middleware/tracing"
+
+// Config defines the configuration for the tracing middleware
+type Config struct {
+	// TracerProvider creates the tracer. Default is the global provider.
+	TracerProvider trace.TracerProvider
+
+	// Propagators extract the incoming trace context from request headers.
+	// Default is the global propagator.
+	Propagators propagation.TextMapPropagator
+}
+
+// Tracing starts a server span for every request, continuing the trace
+// context sent by the client. Spans are named after the method and matched
+// route pattern, e.g. "GET /users/{id}", record the response status, and
+// record panics before re-raising them. Handlers can reach the span through
+// trace.SpanFromContext(r.Context()).
+func Tracing(config Config) func(http.Handler) http.Handler {
+	if config.TracerProvider == nil {
+		config.TracerProvider = otel.GetTracerProvider()
+	}
+	if config.Propagators == nil {
+		config.Propagators = otel.GetTextMapPropagator()
+	}
+	tracer := config.TracerProvider.Tracer(tracerName)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := config.Propagators.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			route := router.MatchedRoutePattern(r)
+			name := r.Method
+			if route != "" {
+				name += " " + route
+			}
+
+			attributes := []attribute.KeyValue{
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			}
+			if route != "" {
+				attributes = append(attributes, attribute.String("http.route", route))
+			}
+
+			ctx, span := tracer.Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(attributes...),
+			)
+			defer span.End()
+
+			wrappedWriter := middleware.NewResponseWriter(w)
+			defer func() {
+				if err := recover(); err != nil {
+					span.RecordError(fmt.Errorf("panic: %v", err), trace.WithStackTrace(true))
+					span.SetStatus(codes.Error, "panic")
+					panic(err)
+				}
+			}()
+
+			next.ServeHTTP(wrappedWriter, r.WithContext(ctx))
+
+			span.SetAttributes(attribute.Int("http.response.status_code", wrappedWriter.StatusCode))
+			if wrappedWriter.StatusCode >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(wrappedWriter.StatusCode))
+			}
+		})
+	}
+}
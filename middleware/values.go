@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+// Key identifies a request value of type T. Keys are compared by identity, so
+// two keys never collide even if they share a name, and values can't be read
+// back with the wrong type. Create keys once, as package-level variables:
+//
+//	var UserKey = middleware.NewKey[*User]("user")
+type Key[T any] struct {
+	name string
+}
+
+// NewKey creates a key for request values of type T. The name is only used
+// when printing the key.
+func NewKey[T any](name string) *Key[T] {
+	return &Key[T]{name: name}
+}
+
+// String returns the name of the key
+func (k *Key[T]) String() string {
+	return "middleware.Key(" + k.name + ")"
+}
+
+// WithValue returns a shallow copy of r whose context carries value under key,
+// for handlers and middleware further in to read with Value
+func WithValue[T any](r *http.Request, key *Key[T], value T) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), key, value))
+}
+
+// Value returns the value stored under key by WithValue, and whether there
+// was one
+func Value[T any](r *http.Request, key *Key[T]) (T, bool) {
+	value, ok := r.Context().Value(key).(T)
+	return value, ok
+}
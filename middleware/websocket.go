@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"crypto/sha1"
 	"encoding/base64"
+	"errors"
 	"net"
 	"net/http"
 	"strings"
@@ -38,7 +39,7 @@ func WebSocketWithConfig(config WSConfig, handler WSHandler) func(http.Handler)
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Only consider GET requests with the upgrade headers.
-			if !isWebSocketUpgrade(r) {
+			if !IsWebSocketUpgrade(r) {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -62,14 +63,13 @@ func WebSocketWithConfig(config WSConfig, handler WSHandler) func(http.Handler)
 				return
 			}
 
-			// Hijack the connection.
-			hj, ok := w.(http.Hijacker)
-			if !ok {
+			// Hijack the connection, looking through wrapping writers that
+			// implement Unwrap.
+			conn, bufrw, err := http.NewResponseController(w).Hijack()
+			if errors.Is(err, http.ErrNotSupported) {
 				http.Error(w, "Server does not support hijacking", http.StatusInternalServerError)
 				return
 			}
-
-			conn, bufrw, err := hj.Hijack()
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -89,8 +89,10 @@ func WebSocketWithConfig(config WSConfig, handler WSHandler) func(http.Handler)
 	}
 }
 
-// isWebSocketUpgrade checks whether the request is a WebSocket upgrade request.
-func isWebSocketUpgrade(r *http.Request) bool {
+// IsWebSocketUpgrade reports whether the request asks for a WebSocket upgrade.
+// Middleware that buffers responses can use it to pass such requests through,
+// since the connection will be hijacked.
+func IsWebSocketUpgrade(r *http.Request) bool {
 	if r.Method != http.MethodGet {
 		return false
 	}
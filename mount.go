@@ -0,0 +1,110 @@
+package router
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jtclarkjr/router-go/internal/routectx"
+)
+
+// standardMethods are the methods a mounted handler is registered for
+var standardMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodConnect,
+	http.MethodOptions,
+	http.MethodTrace,
+}
+
+// MountConfig defines options for mounting a handler under a prefix
+type MountConfig struct {
+	// PreservePath passes the original request path to the mounted handler
+	// instead of stripping the prefix from it
+	PreservePath bool
+}
+
+// Mount attaches an arbitrary http.Handler, such as a pprof or metrics mux,
+// under a path prefix. Every standard method on the prefix and every path below
+// it is delegated to the handler, with the prefix stripped from the request
+// path and the router's middleware applied first.
+func (r *Router) Mount(prefix string, handler http.Handler) {
+	r.MountWithConfig(prefix, handler, MountConfig{})
+}
+
+// MountWithConfig attaches a handler under a prefix with the provided
+// configuration
+func (r *Router) MountWithConfig(prefix string, handler http.Handler, config MountConfig) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if !config.PreservePath {
+		handler = stripPrefix(handler)
+	}
+
+	for _, method := range standardMethods {
+		r.Handle(method, prefix+"/*", handler)
+	}
+}
+
+// stripPrefix removes the mount prefix from the request path, like
+// http.StripPrefix, but leaves "/" rather than an empty path when the prefix
+// itself is requested. The prefix is read from the matched pattern, so it
+// includes the prefixes of enclosing groups, and is removed by its number of
+// segments, which also strips prefixes with parameters like /orgs/{orgID}.
+func stripPrefix(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		pattern := routectx.Pattern(req.Context())
+		segments := strings.Count(strings.TrimSuffix(pattern, "/*"), "/")
+
+		u := new(url.URL)
+		*u = *req.URL
+		if escaped, _ := req.Context().Value(escapedPathKey).(bool); escaped {
+			// The route was matched against the escaped path, where an encoded
+			// slash doesn't end a segment, so strip that and decode the rest
+			rawPath := stripSegments(u.EscapedPath(), segments)
+			path, err := url.PathUnescape(rawPath)
+			if err != nil {
+				path = stripSegments(u.Path, segments)
+			}
+			u.Path, u.RawPath = path, ""
+			if u.EscapedPath() != rawPath {
+				u.RawPath = rawPath
+			}
+		} else {
+			u.Path = stripSegments(u.Path, segments)
+
+			// Keep the escaped form only if it still encodes the same path,
+			// which it doesn't when the prefix held an encoded slash
+			if u.RawPath != "" {
+				u.RawPath = stripSegments(u.RawPath, segments)
+				if decoded, err := url.PathUnescape(u.RawPath); err != nil || decoded != u.Path {
+					u.RawPath = ""
+				}
+			}
+		}
+
+		stripped := new(http.Request)
+		*stripped = *req
+		stripped.URL = u
+		handler.ServeHTTP(w, stripped)
+	})
+}
+
+// stripSegments removes the first n segments of path, leaving "/" when
+// nothing remains
+func stripSegments(path string, n int) string {
+	for range n {
+		next := strings.IndexByte(path[min(1, len(path)):], '/')
+		if next < 0 {
+			return "/"
+		}
+		path = path[next+1:]
+	}
+	if path == "" {
+		return "/"
+	}
+	return path
+}
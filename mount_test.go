@@ -0,0 +1,80 @@
+package router
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jtclarkjr/router-go/routertest"
+)
+
+// pathHandler writes the request path and, after a space, the raw path
+func pathHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(r.URL.Path + " " + r.URL.RawPath))
+}
+
+func TestMountParamPrefix(t *testing.T) {
+	r := NewRouter()
+	r.Mount("/orgs/{orgID}", http.HandlerFunc(pathHandler))
+
+	rec := routertest.Get(r, "/orgs/acme/repos")
+	if got, want := rec.Body.String(), "/repos "; got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+func TestMountEscapedPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		escaped bool
+		target  string
+		want    string
+	}{
+		// The encoded slash is part of the orgID segment, so only /x remains
+		{"escaped prefix", true, "/orgs/a%2Fb/x", "/x "},
+		{"escaped rest", true, "/orgs/acme/a%2Fb", "/a/b /a%2Fb"},
+		// Matched against the decoded path, the slash ends the segment
+		{"decoded", false, "/orgs/a%2Fb/x", "/b/x "},
+		{"decoded rest", false, "/orgs/acme/a%2Fb", "/a/b /a%2Fb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRouter()
+			r.UseEscapedPath(tt.escaped)
+			r.Mount("/orgs/{orgID}", http.HandlerFunc(pathHandler))
+
+			rec := routertest.Get(r, tt.target)
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("GET %s: body = %q, want %q", tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMountInGroup(t *testing.T) {
+	r := NewRouter()
+	r.Route("/api", func(api *Router) {
+		api.Mount("/debug", http.HandlerFunc(pathHandler))
+		api.MountWithConfig("/raw", http.HandlerFunc(pathHandler), MountConfig{PreservePath: true})
+	})
+
+	tests := []struct {
+		method string
+		target string
+		want   string
+	}{
+		{http.MethodGet, "/api/debug/pprof/heap", "/pprof/heap "},
+		{http.MethodPost, "/api/debug/x", "/x "},
+		{http.MethodGet, "/api/debug", "/ "},
+		{http.MethodGet, "/api/raw/x", "/api/raw/x "},
+	}
+	for _, tt := range tests {
+		rec := routertest.Request(r, tt.method, tt.target, nil)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s %s: status = %d, want 200", tt.method, tt.target, rec.Code)
+			continue
+		}
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("%s %s: body = %q, want %q", tt.method, tt.target, got, tt.want)
+		}
+	}
+}
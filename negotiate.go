@@ -0,0 +1,129 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Negotiate returns the offered media type the client prefers according to
+// its Accept header, or "" if it accepts none of them. Quality values and the
+// wildcards */* and type/* are honored; for each offer the most specific
+// matching range decides its quality, and ties go to the earlier offer. A
+// request without an Accept header gets the first offer.
+//
+//	switch router.Negotiate(r, "application/json", "text/html") {
+//	case "text/html":
+//		renderPage(w, data)
+//	default:
+//		router.JSON(w, http.StatusOK, data)
+//	}
+func Negotiate(r *http.Request, offers ...string) string {
+	return negotiate(r.Header.Values("Accept"), offers, matchMediaRange)
+}
+
+// NegotiateLanguage returns the offered language tag the client prefers
+// according to its Accept-Language header, or "" if it accepts none of them.
+// A range matches a tag equal to it or starting with it and a "-", so "en"
+// matches "en-US", and "*" matches any tag. A request without an
+// Accept-Language header gets the first offer.
+func NegotiateLanguage(r *http.Request, offers ...string) string {
+	return negotiate(r.Header.Values("Accept-Language"), offers, matchLanguageRange)
+}
+
+// acceptRange is one entry of an Accept-style header
+type acceptRange struct {
+	value   string
+	quality float64
+}
+
+// negotiate picks the offer with the highest quality among the header's
+// ranges. match reports whether a range covers an offer, and how
+// specifically, with higher numbers being more specific.
+func negotiate(header []string, offers []string, match func(rng, offer string) (int, bool)) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	ranges := parseAccept(header)
+	if len(ranges) == 0 {
+		return offers[0]
+	}
+
+	best, bestQuality := "", 0.0
+	for _, offer := range offers {
+		quality, specificity := 0.0, -1
+		for _, rng := range ranges {
+			if level, ok := match(rng.value, offer); ok && level > specificity {
+				quality, specificity = rng.quality, level
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = offer, quality
+		}
+	}
+	return best
+}
+
+// parseAccept splits Accept-style header values into ranges with their
+// quality values, dropping other parameters and entries with an invalid q
+func parseAccept(header []string) []acceptRange {
+	var ranges []acceptRange
+	for _, value := range header {
+		for _, entry := range strings.Split(value, ",") {
+			fields := strings.Split(entry, ";")
+			rng := acceptRange{value: strings.ToLower(strings.TrimSpace(fields[0])), quality: 1}
+			if rng.value == "" {
+				continue
+			}
+			valid := true
+			for _, param := range fields[1:] {
+				name, q, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(name), "q") {
+					continue
+				}
+				quality, err := strconv.ParseFloat(strings.TrimSpace(q), 64)
+				if err != nil || quality < 0 || quality > 1 {
+					valid = false
+					break
+				}
+				rng.quality = quality
+			}
+			if valid {
+				ranges = append(ranges, rng)
+			}
+		}
+	}
+	return ranges
+}
+
+// matchMediaRange matches a media range such as "text/*" against an offered
+// media type, ignoring the offer's parameters
+func matchMediaRange(rng, offer string) (int, bool) {
+	offer, _, _ = strings.Cut(strings.ToLower(offer), ";")
+	offer = strings.TrimSpace(offer)
+	if rng == "*/*" || rng == "*" {
+		return 0, true
+	}
+	rangeType, rangeSub, _ := strings.Cut(rng, "/")
+	offerType, offerSub, _ := strings.Cut(offer, "/")
+	if rangeType != offerType {
+		return 0, false
+	}
+	if rangeSub == "*" {
+		return 1, true
+	}
+	return 2, rangeSub == offerSub
+}
+
+// matchLanguageRange matches a language range against an offered tag. Longer
+// ranges are more specific.
+func matchLanguageRange(rng, offer string) (int, bool) {
+	offer = strings.ToLower(offer)
+	if rng == "*" {
+		return 0, true
+	}
+	if offer == rng || strings.HasPrefix(offer, rng+"-") {
+		return len(rng), true
+	}
+	return 0, false
+}
@@ -0,0 +1,144 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Errors returned by the typed URL parameter accessors
+var (
+	ErrParamNotFound = errors.New("router: URL parameter not found")
+	ErrInvalidParam  = errors.New("router: invalid URL parameter")
+)
+
+// ConstraintError describes a URL parameter whose value failed its
+// constraint, as recorded by ConstraintDiagnostics
+type ConstraintError struct {
+	// Pattern is the route that would have matched, e.g. /users/{id:int}
+	Pattern string
+
+	// Param is the name of the rejected parameter
+	Param string
+
+	// Constraint is the constraint as written in the pattern, such as "int"
+	// or "[a-z]+"
+	Constraint string
+
+	// Value is the rejected path segment
+	Value string
+}
+
+// Error implements the error interface
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("router: value %q for parameter %q does not match %s", e.Value, e.Param, e.Constraint)
+}
+
+// uuidPattern matches a UUID in its canonical 8-4-4-4-12 form
+var uuidPattern = regexp.MustCompile(`^` + constraints["uuid"] + `$`)
+
+// urlParam looks up a URL parameter, reporting whether the route captured it
+func urlParam(r *http.Request, key string) (string, bool) {
+	params, _ := r.Context().Value(paramsKey).([]paramValue)
+	for i := len(params) - 1; i >= 0; i-- {
+		if params[i].key == key {
+			return params[i].value, true
+		}
+	}
+	return "", false
+}
+
+// URLParamInt parses a URL parameter as an int. It returns ErrParamNotFound if
+// the route has no such parameter, and an error wrapping ErrInvalidParam if
+// the value isn't an integer.
+func URLParamInt(r *http.Request, key string) (int, error) {
+	value, ok := urlParam(r, key)
+	if !ok {
+		return 0, ErrParamNotFound
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, invalidParam(key, err)
+	}
+	return n, nil
+}
+
+// URLParamInt64 parses a URL parameter as an int64, with the errors of
+// URLParamInt
+func URLParamInt64(r *http.Request, key string) (int64, error) {
+	value, ok := urlParam(r, key)
+	if !ok {
+		return 0, ErrParamNotFound
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, invalidParam(key, err)
+	}
+	return n, nil
+}
+
+// URLParamUUID validates a URL parameter as a UUID and returns it in lower
+// case, with the errors of URLParamInt
+func URLParamUUID(r *http.Request, key string) (string, error) {
+	value, ok := urlParam(r, key)
+	if !ok {
+		return "", ErrParamNotFound
+	}
+	if !uuidPattern.MatchString(value) {
+		return "", invalidParam(key, fmt.Errorf("%q is not a UUID", value))
+	}
+	return strings.ToLower(value), nil
+}
+
+// URLParamBool parses a URL parameter as a bool, accepting the values
+// strconv.ParseBool does, with the errors of URLParamInt
+func URLParamBool(r *http.Request, key string) (bool, error) {
+	value, ok := urlParam(r, key)
+	if !ok {
+		return false, ErrParamNotFound
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, invalidParam(key, err)
+	}
+	return b, nil
+}
+
+// invalidParam wraps a parse error with ErrInvalidParam and the parameter name
+func invalidParam(key string, err error) error {
+	return fmt.Errorf("%w %q: %w", ErrInvalidParam, key, err)
+}
+
+// URLQueryDefault returns a query parameter, or fallback when it is absent or
+// empty, so both "?page=" and no page at all give the fallback
+func URLQueryDefault(r *http.Request, key, fallback string) string {
+	if value := r.URL.Query().Get(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// URLQueryInt parses a query parameter as an int. It returns ErrParamNotFound
+// if the parameter is absent, and an error wrapping ErrInvalidParam if it
+// isn't an integer, which includes an empty value such as "?page=".
+func URLQueryInt(r *http.Request, key string) (int, error) {
+	values, ok := r.URL.Query()[key]
+	if !ok || len(values) == 0 {
+		return 0, ErrParamNotFound
+	}
+	n, err := strconv.Atoi(values[0])
+	if err != nil {
+		return 0, invalidParam(key, err)
+	}
+	return n, nil
+}
+
+// URLQuerySlice returns every value of a repeated query parameter, such as
+// ["a", "b"] for "?tag=a&tag=b", in order. Empty values are kept, so "?tag="
+// gives [""], and an absent parameter gives nil.
+func URLQuerySlice(r *http.Request, key string) []string {
+	return r.URL.Query()[key]
+}
@@ -0,0 +1,250 @@
+package router
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// constraints maps named parameter constraints to the regex they expand to
+var constraints = map[string]string{
+	"int":   `[0-9]+`,
+	"alpha": `[a-zA-Z]+`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+// anchoredConstraints caches the compiled, anchored form of each constraint
+// regex, as URLFor checks values against them on every call
+var anchoredConstraints sync.Map
+
+// anchoredConstraint returns a regex matching values that satisfy constraint
+// in full. The constraint must already have been validated.
+func anchoredConstraint(constraint string) *regexp.Regexp {
+	if cached, ok := anchoredConstraints.Load(constraint); ok {
+		return cached.(*regexp.Regexp)
+	}
+	compiled, _ := anchoredConstraints.LoadOrStore(constraint, regexp.MustCompile("^(?:"+constraint+")$"))
+	return compiled.(*regexp.Regexp)
+}
+
+// RegisterConstraint adds a named parameter constraint usable as {param:name}.
+// It should be called before any routes using it are registered.
+func RegisterConstraint(name, pattern string) {
+	constraints[name] = pattern
+}
+
+// pathParam is a parameter placeholder parsed from a path template
+type pathParam struct {
+	name       string
+	constraint string // regex the value must match, empty when unconstrained
+	catchAll   bool
+	optional   bool // {name?}, which may be left out along with its segment
+	start, end int  // byte offsets of the placeholder, braces included
+}
+
+// parseParams finds the parameter placeholders in a path template. Braces
+// inside a constraint are balanced, so {code:[a-z]{3}} is a single parameter.
+func parseParams(path string) []pathParam {
+	var params []pathParam
+	for i := 0; i < len(path); i++ {
+		if path[i] != '{' {
+			continue
+		}
+
+		depth := 0
+		end := -1
+		for j := i; j < len(path); j++ {
+			switch path[j] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+			if depth == 0 {
+				end = j + 1
+				break
+			}
+		}
+		if end == -1 {
+			break
+		}
+
+		param := pathParam{start: i, end: end}
+		body := path[i+1 : end-1]
+		if name, constraint, ok := strings.Cut(body, ":"); ok {
+			param.name = name
+			param.constraint = constraint
+			if named, ok := constraints[constraint]; ok {
+				param.constraint = named
+			}
+		} else if name, ok := strings.CutSuffix(body, "..."); ok {
+			param.name = name
+			param.catchAll = true
+		} else if name, ok := strings.CutSuffix(body, "?"); ok {
+			param.name = name
+			param.optional = true
+		} else {
+			param.name = body
+		}
+
+		params = append(params, param)
+		i = end - 1
+	}
+	return params
+}
+
+// compilePattern builds a regex for a template, using fallback for the value
+// of any parameter without a constraint. Each parameter is captured by a
+// group named p0, p1, ... so groups inside constraints don't shift them. A
+// trailing "/*" matches the prefix itself or anything below it, the same as
+// in the routing tree; a "*" anywhere else is literal.
+func compilePattern(template string, params []pathParam, fallback string) *regexp.Regexp {
+	template, wildcard := strings.CutSuffix(template, "/*")
+
+	var b strings.Builder
+	b.WriteString("^")
+	last := 0
+	for i, param := range params {
+		literal := template[last:param.start]
+		expr := fallback
+		if param.catchAll {
+			expr = `.+`
+		} else if param.constraint != "" {
+			expr = param.constraint
+		}
+		last = param.end
+
+		// An optional parameter takes its leading slash with it, except at the
+		// root where "/" itself must still match
+		if param.optional && param.start > 1 {
+			b.WriteString(regexp.QuoteMeta(strings.TrimSuffix(literal, "/")))
+			fmt.Fprintf(&b, "(?:/(?P<p%d>%s))?", i, expr)
+			continue
+		}
+		b.WriteString(regexp.QuoteMeta(literal))
+		if param.optional {
+			fmt.Fprintf(&b, "(?P<p%d>%s)?", i, expr)
+			continue
+		}
+		fmt.Fprintf(&b, "(?P<p%d>%s)", i, expr)
+	}
+	b.WriteString(regexp.QuoteMeta(template[last:]))
+	if wildcard {
+		b.WriteString(`(?:/.*)?`)
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+// groupIndexes returns the submatch index of each parameter in a pattern
+// built by compilePattern
+func groupIndexes(pattern *regexp.Regexp, count int) []int {
+	indexes := make([]int, count)
+	for i := range indexes {
+		indexes[i] = pattern.SubexpIndex(fmt.Sprintf("p%d", i))
+	}
+	return indexes
+}
+
+// validatePattern panics with a message naming the offending template when a
+// path has unbalanced braces, an empty, invalid or repeated parameter name, a
+// constraint that is empty, unknown or not a valid regular expression, or a
+// catch-all or optional parameter that isn't the whole last segment.
+// Parameters can't span a "/", since the tree matches one segment at a time.
+func validatePattern(path string) {
+	invalid := func(format string, args ...any) {
+		panic(fmt.Sprintf("router: invalid pattern %q: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	seen := make(map[string]bool)
+	segments := splitPath(path)
+	for i, segment := range segments {
+		final := i == len(segments)-1
+		if segment == "*" && !final {
+			invalid("wildcard * must be the last segment, so nothing after it can be matched")
+		}
+
+		params := parseParams(segment)
+		for _, param := range params {
+			whole := len(params) == 1 && param.start == 0 && param.end == len(segment)
+			if param.catchAll && (!whole || !final) {
+				invalid("catch-all {%s...} must be the whole last segment, so nothing after it can be matched", param.name)
+			}
+			if param.optional && (!whole || !final) {
+				invalid("optional parameter {%s?} must be the whole last segment", param.name)
+			}
+		}
+
+		last := 0
+		for _, param := range params {
+			if strings.ContainsAny(segment[last:param.start], "{}") {
+				invalid("unbalanced braces in segment %q", segment)
+			}
+			last = param.end
+
+			raw := segment[param.start:param.end]
+			if !validParamName(param.name) {
+				invalid("invalid parameter name in %s", raw)
+			}
+			if seen[param.name] {
+				invalid("parameter %q appears more than once", param.name)
+			}
+			seen[param.name] = true
+
+			_, constraint, ok := strings.Cut(raw[1:len(raw)-1], ":")
+			if !ok {
+				continue
+			}
+			if constraint == "" {
+				invalid("empty constraint in %s", raw)
+			}
+			if _, named := constraints[constraint]; !named && validParamName(constraint) {
+				invalid("unknown constraint %q in %s", constraint, raw)
+			}
+			if _, err := regexp.Compile(param.constraint); err != nil {
+				invalid("constraint in %s is not a valid regular expression: %v", raw, err)
+			}
+		}
+		if strings.ContainsAny(segment[last:], "{}") {
+			invalid("unbalanced braces in segment %q", segment)
+		}
+	}
+}
+
+// validParamName reports whether name is non-empty and made up of ASCII
+// letters, digits and underscores, not starting with a digit
+func validParamName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// colonToBraces translates a template in the colon syntax enabled by
+// ColonParams, where a segment is :name or a trailing *name, into the brace
+// syntax. It panics if the template uses braces, so the two styles can't mix.
+func colonToBraces(path string) string {
+	if strings.ContainsAny(path, "{}") {
+		panic(fmt.Sprintf("router: invalid pattern %q: braces can't be used with ColonParams", path))
+	}
+
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		switch {
+		case strings.HasPrefix(segment, ":"):
+			segments[i] = "{" + segment[1:] + "}"
+		case strings.HasPrefix(segment, "*") && segment != "*":
+			segments[i] = "{" + segment[1:] + "...}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
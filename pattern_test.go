@@ -0,0 +1,73 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/jtclarkjr/router-go/routertest"
+)
+
+// registerPanic registers pattern on a new router and returns the panic
+// message, or "" if it didn't panic
+func registerPanic(pattern string) (msg string) {
+	defer func() {
+		if v := recover(); v != nil {
+			msg = fmt.Sprint(v)
+		}
+	}()
+	NewRouter().Get(pattern, func(http.ResponseWriter, *http.Request) {})
+	return ""
+}
+
+func TestMalformedPatternsPanic(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"/users/{}", "invalid parameter name"},
+		{"/users/{id", "unbalanced braces"},
+		{"/users/id}", "unbalanced braces"},
+		{"/users/{a b}", "invalid parameter name"},
+		{"/users/{id}/{id}", `parameter "id" appears more than once`},
+		{"/users/{id:}", "empty constraint"},
+		{"/users/{id:nope}", `unknown constraint "nope"`},
+		{"/users/{id:[0-9}", "not a valid regular expression"},
+		{"/users/{id:(a}", "not a valid regular expression"},
+		{"/files/*/more", "wildcard * must be the last segment"},
+		{"/files/{path...}/more", "catch-all {path...} must be the whole last segment"},
+		{"/posts/{slug?}/edit", "optional parameter {slug?} must be the whole last segment"},
+	}
+	for _, tt := range tests {
+		msg := registerPanic(tt.pattern)
+		if msg == "" {
+			t.Errorf("%s: registered without a panic", tt.pattern)
+			continue
+		}
+		if !strings.Contains(msg, tt.want) || !strings.Contains(msg, fmt.Sprintf("%q", tt.pattern)) {
+			t.Errorf("%s: panic %q, want it to name the pattern and mention %q", tt.pattern, msg, tt.want)
+		}
+	}
+}
+
+func TestRegexMetacharactersMatchLiterally(t *testing.T) {
+	r := NewRouter()
+	r.Get("/files/v1.0/a+b", nameHandler("literal"))
+	r.Get("/price/$5", nameHandler("dollar"))
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{"/files/v1.0/a+b", http.StatusOK},
+		{"/files/v1x0/a+b", http.StatusNotFound},
+		{"/files/v1.0/aab", http.StatusNotFound},
+		{"/price/$5", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if rec := routertest.Get(r, tt.path); rec.Code != tt.code {
+			t.Errorf("GET %s: status = %d, want %d", tt.path, rec.Code, tt.code)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/jtclarkjr/router-go/middleware"
+)
+
+// JSON writes v as a JSON response with the given status. v is encoded before
+// anything is written, so an encoding error is logged and answered with a 500
+// instead of a truncated body, and then returned. Pass a status of 0 if the
+// handler has already called WriteHeader, and the status isn't written again.
+// Behind a middleware.ResponseWriter, such as the one Logger uses, a status
+// already written is detected and not written again whatever status is passed.
+func JSON(w http.ResponseWriter, status int, v any) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		log.Printf("router: encoding JSON response: %v", err)
+		if status != 0 && !headerWritten(w) {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return err
+	}
+
+	if status != 0 && !headerWritten(w) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Render writes v as a JSON response with the given status, like JSON
+func Render(w http.ResponseWriter, status int, v any) error {
+	return JSON(w, status, v)
+}
+
+// String writes s as a plain text response with the given status. As with
+// JSON, a status of 0, or one already written behind a
+// middleware.ResponseWriter, skips writing the status.
+func String(w http.ResponseWriter, status int, s string) error {
+	if status != 0 && !headerWritten(w) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+	}
+	_, err := w.Write([]byte(s))
+	return err
+}
+
+// headerWritten reports whether a status has already been written to w, as
+// recorded by a middleware.ResponseWriter it is or wraps
+func headerWritten(w http.ResponseWriter) bool {
+	for {
+		switch rw := w.(type) {
+		case *middleware.ResponseWriter:
+			return rw.Written()
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// NoContent writes an empty 204 No Content response
+func NoContent(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,45 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jtclarkjr/router-go/middleware"
+)
+
+// headerCounter counts the calls to WriteHeader that reach it
+type headerCounter struct {
+	http.ResponseWriter
+	calls int
+}
+
+func (w *headerCounter) WriteHeader(code int) {
+	w.calls++
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func TestRenderAfterWriteHeader(t *testing.T) {
+	render := map[string]func(w http.ResponseWriter) error{
+		"JSON":   func(w http.ResponseWriter) error { return JSON(w, http.StatusOK, map[string]int{"a": 1}) },
+		"String": func(w http.ResponseWriter) error { return String(w, http.StatusOK, "ok") },
+	}
+	for name, fn := range render {
+		t.Run(name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			counter := &headerCounter{ResponseWriter: rec}
+			w := middleware.NewResponseWriter(counter)
+
+			w.WriteHeader(http.StatusAccepted)
+			if err := fn(w); err != nil {
+				t.Fatal(err)
+			}
+			if counter.calls != 1 || rec.Code != http.StatusAccepted {
+				t.Errorf("WriteHeader called %d times, status = %d, want once with 202", counter.calls, rec.Code)
+			}
+			if rec.Body.Len() == 0 {
+				t.Error("body not written")
+			}
+		})
+	}
+}
@@ -3,9 +3,15 @@ package router
 import (
 	"context"
 	"net/http"
+	"net/url"
+	"path"
 	"regexp"
+	"slices"
 	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/jtclarkjr/router-go/internal/routectx"
 	"github.com/jtclarkjr/router-go/middleware"
 )
 
@@ -14,82 +20,461 @@ type Middleware func(http.Handler) http.Handler
 
 // Route stores information about a route, including its handler and parameter keys
 type Route struct {
-	Handler      http.Handler
-	ParamKeys    []string
+	Handler   http.Handler
+	Pattern   string
+	ParamKeys []string
+
+	// ParamPattern is no longer set: the routing tree matches requests and
+	// captures parameters itself, so no regex is compiled per route.
+	//
+	// Deprecated: Use ParamKeys, and URLParam to read values.
 	ParamPattern *regexp.Regexp
+
+	// anyMethod is set for routes registered by Any
+	anyMethod bool
+
+	// middlewares are the middleware Handler is wrapped with, outermost first
+	middlewares []Middleware
+
+	// corsPolicy identifies the innermost CORS middleware among them, or is nil
+	corsPolicy any
 }
 
 // Router is a custom router that maps methods and paths to handlers
 type Router struct {
-	routes     map[string]map[string]Route
-	middleware []Middleware
+	tree         *node
+	middleware   []Middleware
+	notFound     http.Handler     // notFoundFn or the default 404, wrapped with middleware
+	notFoundFn   http.HandlerFunc // the handler set by NotFound, for handlers already inside it
+	notAllowed   http.Handler     // notAllowedFn or the default 405, wrapped with middleware
+	notAllowedFn http.HandlerFunc // the handler set by MethodNotAllowed
+
+	// names maps route names to their path templates
+	names map[string]string
+
+	// routeName is the name given to routes registered through a router
+	// returned by Name
+	routeName string
+
+	// strictHead disables answering HEAD requests with GET routes
+	strictHead bool
+
+	// optionsEnabled answers OPTIONS requests for paths without an OPTIONS
+	// route with autoOptions, the responder wrapped with middleware
+	optionsEnabled bool
+	autoOptions    http.Handler
+
+	// redirectSlash redirects misses to the path with its trailing slash
+	// toggled when that path has a route
+	redirectSlash bool
+
+	// cleanPath redirects requests whose paths aren't in canonical form
+	cleanPath bool
+
+	// colonParams makes patterns use :name and *name instead of braces
+	colonParams bool
+
+	// escapedPath matches routes against the escaped request path
+	escapedPath bool
+
+	// constraintDiagnostics records constraint failures for NotFound
+	constraintDiagnostics bool
+
+	// errorHandler responds to errors returned by HandlerFuncE handlers
+	errorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+	// handlesErrors is set once HandleE routes are registered on the router
+	// or its groups, whose handlers hold on to errorHandler
+	handlesErrors bool
+
+	// server is the http.Server used by ListenAndServe
+	server *http.Server
+
+	// shutdownTimeout bounds how long Shutdown waits for in-flight requests
+	shutdownTimeout time.Duration
+
+	// inFlight counts the requests being served when tracking is enabled
+	inFlight *atomic.Int64
+
+	// hosts holds the routes registered with Host. It is nil for the
+	// subrouters of groups and hosts.
+	hosts *hostTable
+
+	// parent is the router that routers returned by Route, Host, With and
+	// Name register their routes on, and nil for the router serving them
+	parent *Router
 }
 
 // NewRouter creates a new Router instance
 func NewRouter() *Router {
-	return &Router{
-		routes:     make(map[string]map[string]Route),
+	r := &Router{
+		tree:       newNode(),
 		middleware: []Middleware{},
+		names:      make(map[string]string),
+		hosts:      &hostTable{},
 	}
+	r.wrapFallbacks()
+	return r
 }
 
-// Route creates a subrouter for the given path prefix
+// Route creates a subrouter for the given path prefix. Groups can be nested to
+// any depth, with prefixes concatenating and middleware accumulating at each
+// level.
+//
+// The subrouter starts with the middleware registered on r, and those wrap
+// every route in the group exactly once, outside the group's own middleware.
+// Middleware added inside fn only applies to the group's routes.
+//
+// Requests that match no route are answered by the top-level router, so
+// NotFound, MethodNotAllowed and AutoOptions panic when called on the
+// subrouter.
 func (r *Router) Route(pathPrefix string, fn func(router *Router)) {
-	// Create a new subrouter
-	subrouter := &Router{
-		routes:     make(map[string]map[string]Route),
-		middleware: make([]Middleware, len(r.middleware)),
+	if r.colonParams {
+		pathPrefix = colonToBraces(pathPrefix)
 	}
 
-	// Copy parent middleware
-	copy(subrouter.middleware, r.middleware)
+	// Create a new subrouter with its own copy of the parent middleware, so
+	// Use on either side never leaks into the other
+	subrouter := &Router{
+		tree:         newNode(),
+		middleware:   slices.Clone(r.middleware),
+		names:        make(map[string]string),
+		colonParams:  r.colonParams,
+		errorHandler: r.errorHandler,
+		parent:       r,
+	}
 
 	// Execute the routing function on the subrouter
 	fn(subrouter)
 
-	// For each route in the subrouter, add it to the parent router with the prefix
-	for path, methods := range subrouter.routes {
-		fullPath := pathPrefix + path
-		for method, route := range methods {
-			if r.routes[fullPath] == nil {
-				r.routes[fullPath] = make(map[string]Route)
-			}
-			r.routes[fullPath][method] = route
-		}
+	// For each route in the subrouter, add it to the parent router with the
+	// prefix. Handlers are already wrapped, so they are stored as they are.
+	subrouter.tree.walk(func(pattern, method string, route Route) {
+		r.addRoute(method, joinPath(pathPrefix, pattern), route)
+	})
+	for name, pattern := range subrouter.names {
+		r.addName(name, joinPath(pathPrefix, pattern))
 	}
 }
 
-// Use adds a middleware to the router
-func (r *Router) Use(mw Middleware) {
-	r.middleware = append(r.middleware, mw)
+// root returns the router that serves the routes registered on r
+func (r *Router) root() *Router {
+	for r.parent != nil {
+		r = r.parent
+	}
+	return r
+}
+
+// joinPath prefixes a group's route pattern, so nested groups concatenate
+// ("/api" + "/v1" + "/users") without doubling a slash at the joins
+func joinPath(prefix, pattern string) string {
+	if strings.HasPrefix(pattern, "/") {
+		prefix = strings.TrimSuffix(prefix, "/")
+	}
+	return prefix + pattern
+}
+
+// With returns a router that registers routes with additional middleware,
+// applied after the router's own. The routes are added to the same router:
+//
+//	r.With(middleware.BodyLimit(10 << 20)).Post("/upload", h)
+func (r *Router) With(mws ...Middleware) *Router {
+	inline := *r
+	inline.middleware = append(slices.Clip(r.middleware), mws...)
+	inline.parent = r
+	return &inline
+}
+
+// Name returns a router that registers the next route under the given name,
+// so its URL can later be built with URLFor:
+//
+//	r.Name("user.show").Get("/users/{id}", h)
+func (r *Router) Name(name string) *Router {
+	named := *r
+	named.middleware = slices.Clip(r.middleware)
+	named.routeName = name
+	named.parent = r
+	return &named
+}
+
+// addName records the template for a named route. It panics if the name is
+// already used by a different template.
+func (r *Router) addName(name, pattern string) {
+	if existing, ok := r.names[name]; ok && existing != pattern {
+		panic("router: route name " + name + " is already registered for " + existing)
+	}
+	r.names[name] = pattern
+}
+
+// RequireEnv panics if any of the given environment variables is empty, so a
+// server missing its configuration fails at startup instead of serving errors
+func (r *Router) RequireEnv(envVars ...string) {
+	if err := middleware.CheckEnvVars(envVars...); err != nil {
+		panic("router: " + err.Error())
+	}
+}
+
+// Use adds middlewares to the router. Middleware runs in registration order,
+// outermost first: the router's own, then its enclosing groups' inner ones,
+// then those given to With. Several can be added at once, so
+// r.Use(a, b) is the same as r.Use(a) followed by r.Use(b).
+//
+// Use panics if routes are already registered on the router, because they
+// would silently run without the middleware. That includes routes of groups
+// added with Route or Host, so a group always runs its parent's full stack
+// first.
+func (r *Router) Use(mws ...Middleware) {
+	if !r.tree.empty() || r.hosts != nil && len(r.hosts.hosts) > 0 {
+		panic("router: Use must be called before routes are registered")
+	}
+	r.middleware = append(r.middleware, mws...)
+	r.wrapFallbacks()
 }
 
 // Handle registers a handler for a specific method and path
 func (r *Router) Handle(method, path string, handler http.Handler) {
-	// Apply middleware to the handler
+	r.handle(method, path, handler, false)
+}
+
+// handle names, wraps and stores a route. anyMethod marks routes registered
+// by Any, which give way to routes registered for their method explicitly.
+func (r *Router) handle(method, path string, handler http.Handler, anyMethod bool) {
+	if r.colonParams {
+		path = colonToBraces(path)
+	}
+	if r.routeName != "" {
+		r.addName(r.routeName, path)
+	}
+	handler, corsPolicy := r.wrapRoute(handler)
+	r.addRoute(method, path, Route{
+		Handler:     handler,
+		anyMethod:   anyMethod,
+		middlewares: slices.Clip(r.middleware),
+		corsPolicy:  corsPolicy,
+	})
+}
+
+// wrap applies the router's middleware to a handler
+func (r *Router) wrap(handler http.Handler) http.Handler {
+	return Chain(r.middleware...)(handler)
+}
+
+// wrapRoute applies the router's middleware to a route's handler like wrap,
+// also returning the policy of the innermost CORS middleware among them, so
+// outer ones can leave the route's requests to it
+func (r *Router) wrapRoute(handler http.Handler) (http.Handler, any) {
+	var corsPolicy any
 	for i := len(r.middleware) - 1; i >= 0; i-- {
 		handler = r.middleware[i](handler)
+		if cors, ok := handler.(routectx.CORSHandler); ok && corsPolicy == nil {
+			corsPolicy = cors.CORSPolicy()
+		}
+	}
+	return handler, corsPolicy
+}
+
+// Chain composes middlewares into one, in the same order as successive calls
+// to Use: the first is outermost and sees the request first. Using the chain
+// is equivalent to using each of its middlewares in turn:
+//
+//	api := router.Chain(middleware.RequestID, middleware.Logger, middleware.Recoverer)
+//	r.Use(api)
+func Chain(mws ...Middleware) Middleware {
+	mws = slices.Clone(mws)
+	return func(handler http.Handler) http.Handler {
+		for i := len(mws) - 1; i >= 0; i-- {
+			handler = mws[i](handler)
+		}
+		return handler
 	}
+}
+
+// addRoute stores a route with an already wrapped handler in the routing tree,
+// filling in its pattern and parameters from path
+func (r *Router) addRoute(method, path string, route Route) {
+	validatePattern(path)
 
 	// Extract parameter keys from the path
+	params := parseParams(path)
 	paramKeys := []string{}
-	paramPattern := regexp.MustCompile(`\{(\w+)\}`)
-	matches := paramPattern.FindAllStringSubmatch(path, -1)
-	for _, match := range matches {
-		paramKeys = append(paramKeys, match[1])
+	for _, param := range params {
+		paramKeys = append(paramKeys, param.name)
+	}
+
+	route.Pattern = path
+	route.ParamKeys = paramKeys
+	r.tree.insert(method, path, route)
+}
+
+// NotFound sets the handler used when no route matches the request. Like the
+// default 404 it replaces, it runs inside all the router's middleware, whether
+// Use is called before or after it.
+func (r *Router) NotFound(handler http.HandlerFunc) {
+	r.requireTopLevel("NotFound")
+	r.notFoundFn = handler
+	r.wrapFallbacks()
+}
+
+// requireTopLevel panics if r registers its routes on another router, which
+// would never use the fallback handlers set on r
+func (r *Router) requireTopLevel(method string) {
+	if r.parent != nil {
+		panic("router: " + method + " must be called on the top-level router, not inside a group")
+	}
+}
+
+// wrapFallbacks wraps the handlers for requests no route serves with the
+// router's middleware. It runs again whenever either changes, so they always
+// see the same stack as routes.
+func (r *Router) wrapFallbacks() {
+	var notFound http.Handler = http.NotFoundHandler()
+	if r.notFoundFn != nil {
+		notFound = r.notFoundFn
 	}
+	r.notFound = r.wrap(notFound)
 
-	// Replace parameter placeholders with regex patterns
-	regexPath := "^" + paramPattern.ReplaceAllString(path, `([^/]+)`) + "$"
-	compiledPattern := regexp.MustCompile(regexPath)
+	notAllowed := r.notAllowedFn
+	if notAllowed == nil {
+		notAllowed = func(w http.ResponseWriter, req *http.Request) {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+	}
+	r.notAllowed = r.wrap(notAllowed)
 
-	if r.routes[path] == nil {
-		r.routes[path] = make(map[string]Route)
+	r.autoOptions = nil
+	if r.optionsEnabled {
+		r.autoOptions = r.wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
 	}
-	r.routes[path][method] = Route{
-		Handler:      handler,
-		ParamKeys:    paramKeys,
-		ParamPattern: compiledPattern,
+}
+
+// MethodNotAllowed sets the handler used when the path matches a route but the
+// method does not. The Allow header is already set when it runs, and the
+// allowed methods can be read with AllowedMethods. Like NotFound, it runs
+// inside all the router's middleware.
+func (r *Router) MethodNotAllowed(handler http.HandlerFunc) {
+	r.requireTopLevel("MethodNotAllowed")
+	r.notAllowedFn = handler
+	r.wrapFallbacks()
+}
+
+// AutoHead controls whether HEAD requests without an explicit HEAD route are
+// served by the GET route for the same path, with the body discarded. It is
+// enabled by default.
+func (r *Router) AutoHead(enabled bool) {
+	r.strictHead = !enabled
+}
+
+// AutoOptions controls whether OPTIONS requests for paths without an explicit
+// OPTIONS route get a 204 with an Allow header listing the registered methods.
+// It is disabled by default. Like NotFound, the responder runs inside all the
+// router's middleware, so a CORS middleware can still answer preflight
+// requests itself.
+func (r *Router) AutoOptions(enabled bool) {
+	r.requireTopLevel("AutoOptions")
+	r.optionsEnabled = enabled
+	r.wrapFallbacks()
+}
+
+// RedirectTrailingSlash controls whether a request that matches no route is
+// redirected to the same path with its trailing slash added or removed, when
+// that path has a route for the request method. GET and HEAD requests get a
+// 301, other methods a 308 so the method and body are kept. The root path is
+// never redirected. It is disabled by default.
+func (r *Router) RedirectTrailingSlash(enabled bool) {
+	r.redirectSlash = enabled
+}
+
+// TrackInFlight enables counting the requests being served, reported by
+// InFlight. It is off by default and costs a single check per request then.
+func (r *Router) TrackInFlight(enabled bool) {
+	if !enabled {
+		r.inFlight = nil
+		return
+	}
+	if r.inFlight == nil {
+		r.inFlight = new(atomic.Int64)
+	}
+}
+
+// InFlight returns the number of requests being served, or 0 if tracking is
+// disabled. Readiness checks can report it, and shutdown logic can wait for
+// it to reach zero.
+func (r *Router) InFlight() int {
+	if r.inFlight == nil {
+		return 0
+	}
+	return int(r.inFlight.Load())
+}
+
+// CleanPath controls whether requests with a non-canonical path, such as
+// /users//5 or /users/../users/5, are redirected with a 301 to the cleaned path
+// before matching. A trailing slash is kept. Cleaning works on the escaped
+// path, so an encoded slash (%2F) stays part of its segment, while encoded dot
+// segments (%2e%2e) are resolved like plain ones. It is disabled by default.
+func (r *Router) CleanPath(enabled bool) {
+	r.cleanPath = enabled
+}
+
+// UseEscapedPath controls whether routes are matched against the escaped
+// request path, so an encoded slash (%2F) stays inside its segment and
+// /files/a%2Fb matches /files/{name} with name "a/b". Each segment is decoded
+// after splitting, so URLParam still returns decoded values and literal
+// segments match as usual. A catch-all gets the decoded remainder, in which an
+// encoded slash can no longer be told apart from a separator. It is disabled
+// by default, matching against the decoded URL.Path like net/http.
+func (r *Router) UseEscapedPath(enabled bool) {
+	r.escapedPath = enabled
+}
+
+// ConstraintDiagnostics controls whether a request that matches no route, but
+// would match one if a parameter constraint accepted its value, is passed to
+// the NotFound handler with the failure recorded, so the handler can answer
+// with a helpful 400 such as "id must be an integer" instead of a bare 404:
+//
+//	if failure := router.ConstraintFailure(r); failure != nil {
+//		http.Error(w, failure.Param+" is invalid", http.StatusBadRequest)
+//		return
+//	}
+//
+// It is disabled by default. Every request that ends in a 404 walks the tree a
+// second time, and the answers tell clients about routes they didn't reach.
+// Only parameters that make up a whole segment, like {id:int}, are diagnosed.
+func (r *Router) ConstraintDiagnostics(enabled bool) {
+	r.constraintDiagnostics = enabled
+}
+
+// ColonParams switches route patterns to the colon syntax of routers such as
+// Gin and Echo: /users/:id for a parameter and a trailing /files/*path for a
+// catch-all, with bare * still matching without a parameter. Braces are then
+// rejected, so the two styles can't mix. Patterns are stored in the brace
+// syntax, which is what MatchedRoutePattern and Walk report. The default is
+// the brace syntax; ColonParams panics if routes are already registered, and
+// groups inherit the setting.
+func (r *Router) ColonParams(enabled bool) {
+	if !r.tree.empty() || r.hosts != nil && len(r.hosts.hosts) > 0 {
+		panic("router: ColonParams must be called before routes are registered")
+	}
+	r.colonParams = enabled
+}
+
+// Methods registers a handler for each of the given methods on a path. Each
+// method is registered as if by its own call, so the handler is wrapped with
+// the router's middleware once per method.
+func (r *Router) Methods(methods []string, path string, handler http.HandlerFunc) {
+	for _, method := range methods {
+		r.Handle(method, path, handler)
+	}
+}
+
+// Any registers a handler for every standard method on a path: GET, HEAD,
+// POST, PUT, PATCH, DELETE, CONNECT, OPTIONS and TRACE. Routes registered
+// for a specific method on the same path take precedence, whichever is
+// registered first.
+func (r *Router) Any(path string, handler http.HandlerFunc) {
+	for _, method := range standardMethods {
+		r.handle(method, path, handler, true)
 	}
 }
 
@@ -144,49 +529,330 @@ func (r *Router) WS(path string, handler middleware.WSHandler) {
 	r.Handle(http.MethodGet, path, wsMiddleware(http.NotFoundHandler()))
 }
 
-// contextKey is a custom type to avoid collisions in context values
-type contextKey string
+// HandleWebSocket registers a GET route for a handler that upgrades the
+// connection itself, e.g. with a third-party WebSocket library. Requests that
+// aren't upgrades get a 426 Upgrade Required. The buffering middleware of this
+// module (Timeout and ETag) passes upgrades through, and the Logger's writer
+// supports hijacking, so the handler can take over the connection behind
+// them; use http.NewResponseController(w).Hijack to see through wrappers.
+func (r *Router) HandleWebSocket(path string, handler http.Handler) {
+	r.Handle(http.MethodGet, path, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !middleware.IsWebSocketUpgrade(req) {
+			w.Header().Set("Upgrade", "websocket")
+			w.Header().Set("Connection", "Upgrade")
+			http.Error(w, http.StatusText(http.StatusUpgradeRequired), http.StatusUpgradeRequired)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	}))
+}
+
+// routerKey identifies values the router stores in the request context
+type routerKey int
+
+const (
+	allowedMethodsKey routerKey = iota
+
+	// paramsKey stores all URL parameters of the request as one []paramValue
+	paramsKey
+
+	// constraintKey stores the *ConstraintError of an unmatched request
+	constraintKey
+
+	// escapedPathKey marks a request matched against its escaped path, so
+	// Mount and Static strip their prefix from the same form
+	escapedPathKey
+)
 
 // ServeHTTP implements the http.Handler interface
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	for path, methods := range r.routes {
-		route, ok := methods[req.Method]
-		if !ok {
-			continue
+	if r.inFlight != nil {
+		r.inFlight.Add(1)
+		defer r.inFlight.Add(-1)
+	}
+
+	if r.cleanPath && r.redirectCleanPath(w, req) {
+		return
+	}
+
+	tree, hostParams := r.tree, []paramValue(nil)
+	if r.hosts != nil && len(r.hosts.hosts) > 0 {
+		tree, hostParams = r.treeFor(req.Host)
+	}
+
+	segments := r.pathSegments(req.URL)
+	route, params, ok := tree.lookup(req.Method, segments)
+
+	// Serve HEAD from the GET route when there is no explicit HEAD route
+	if !ok && req.Method == http.MethodHead && !r.strictHead {
+		if route, params, ok = tree.lookup(http.MethodGet, segments); ok {
+			w = &headResponseWriter{ResponseWriter: w}
+		}
+	}
+
+	if !ok {
+		if r.redirectSlash && r.redirectTrailingSlash(w, req, tree) {
+			return
 		}
 
-		// Handle wildcard paths (ending with /*)
-		if strings.HasSuffix(path, "/*") {
-			prefix := strings.TrimSuffix(path, "/*")
-			if !strings.HasPrefix(req.URL.Path, prefix) {
-				continue
+		// The path exists under other methods, so this is a 405 rather than a 404
+		if allowed := r.allowedMethods(tree, segments); len(allowed) > 0 {
+			if req.Method == http.MethodOptions && r.autoOptions != nil {
+				r.serveAutoOptions(w, req, allowed)
+				return
 			}
-			route.Handler.ServeHTTP(w, req)
+			r.serveMethodNotAllowed(w, req, allowed)
 			return
 		}
+		if r.constraintDiagnostics {
+			if failure := r.constraintFailure(tree, req.Method, segments); failure != nil {
+				req = req.WithContext(context.WithValue(req.Context(), constraintKey, failure))
+			}
+		}
+		r.serveNotFound(w, req)
+		return
+	}
+
+	// Store every parameter in a single context value. The parameters of an
+	// enclosing router, when this one is mounted, come first so that lookups
+	// from the end find the innermost value.
+	ctx := routectx.WithPattern(req.Context(), route.Pattern)
+	outer, _ := ctx.Value(paramsKey).([]paramValue)
+	if len(outer) > 0 || len(hostParams) > 0 {
+		values := make([]paramValue, 0, len(outer)+len(hostParams)+len(params))
+		params = append(append(append(values, outer...), hostParams...), params...)
+	}
+	if len(params) > 0 {
+		ctx = context.WithValue(ctx, paramsKey, params)
+	}
+	if route.corsPolicy != nil {
+		ctx = routectx.WithCORSPolicy(ctx, route.corsPolicy)
+	}
+	if escaped, _ := ctx.Value(escapedPathKey).(bool); escaped != r.escapedPath {
+		ctx = context.WithValue(ctx, escapedPathKey, r.escapedPath)
+	}
+	route.Handler.ServeHTTP(w, req.WithContext(ctx))
+}
 
-		// Handle parameterized paths
-		matches := route.ParamPattern.FindStringSubmatch(req.URL.Path)
-		if matches == nil {
-			continue
+// constraintFailure finds the constraint that kept a request from matching a
+// route for method, falling back to GET routes for HEAD like ServeHTTP
+func (r *Router) constraintFailure(tree *node, method string, segments []string) *ConstraintError {
+	pattern, failure := tree.constraintFailure(method, segments, nil)
+	if failure == nil && method == http.MethodHead && !r.strictHead {
+		pattern, failure = tree.constraintFailure(http.MethodGet, segments, nil)
+	}
+	if failure == nil {
+		return nil
+	}
+
+	// The tree holds named constraints expanded, so report the one written in
+	// the pattern
+	failure.Pattern = pattern
+	for _, param := range parseParams(pattern) {
+		if param.name == failure.Param {
+			_, failure.Constraint, _ = strings.Cut(pattern[param.start+1:param.end-1], ":")
 		}
+	}
+	return failure
+}
 
-		ctx := req.Context()
-		for i, key := range route.ParamKeys {
-			ctx = context.WithValue(ctx, contextKey(key), matches[i+1])
+// pathSegments splits a request URL's path for matching, using the escaped
+// path and decoding each segment when UseEscapedPath is enabled
+func (r *Router) pathSegments(u *url.URL) []string {
+	if !r.escapedPath {
+		return splitPath(u.Path)
+	}
+	segments := splitPath(u.EscapedPath())
+	for i, segment := range segments {
+		if decoded, err := url.PathUnescape(segment); err == nil {
+			segments[i] = decoded
 		}
-		route.Handler.ServeHTTP(w, req.WithContext(ctx))
-		return
 	}
-	http.NotFound(w, req)
+	return segments
+}
+
+// hasRoute reports whether a request with method and URL would match a route
+// in tree
+func (r *Router) hasRoute(tree *node, method string, u *url.URL) bool {
+	segments := r.pathSegments(u)
+	if _, _, ok := tree.lookup(method, segments); ok {
+		return true
+	}
+	if method == http.MethodHead && !r.strictHead {
+		_, _, ok := tree.lookup(http.MethodGet, segments)
+		return ok
+	}
+	return false
+}
+
+// redirectTrailingSlash redirects to the path with its trailing slash toggled
+// if that path has a route, reporting whether it did. Only redirecting to a
+// path that matches means the redirect can never loop.
+func (r *Router) redirectTrailingSlash(w http.ResponseWriter, req *http.Request, tree *node) bool {
+	path := req.URL.Path
+	if path == "/" || path == "" {
+		return false
+	}
+
+	toggled := toggleTrailingSlash(path)
+	if toggled == "" {
+		return false
+	}
+
+	target := *req.URL
+	target.Path = toggled
+	if target.RawPath != "" {
+		target.RawPath = toggleTrailingSlash(target.RawPath)
+	}
+	if !r.hasRoute(tree, req.Method, &target) {
+		return false
+	}
+
+	code := http.StatusPermanentRedirect
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		code = http.StatusMovedPermanently
+	}
+	http.Redirect(w, req, target.String(), code)
+	return true
+}
+
+// encodedDots decodes percent-encoded dots so dot segments can be resolved
+var encodedDots = strings.NewReplacer("%2e", ".", "%2E", ".")
+
+// redirectCleanPath redirects to the cleaned request path if it differs from
+// the requested one, reporting whether it did
+func (r *Router) redirectCleanPath(w http.ResponseWriter, req *http.Request) bool {
+	escaped := req.URL.EscapedPath()
+	cleaned := cleanPath(encodedDots.Replace(escaped))
+	if cleaned == escaped {
+		return false
+	}
+
+	decoded, err := url.PathUnescape(cleaned)
+	if err != nil {
+		return false
+	}
+
+	target := *req.URL
+	target.Path = decoded
+	target.RawPath = cleaned
+	http.Redirect(w, req, target.String(), http.StatusMovedPermanently)
+	return true
+}
+
+// cleanPath returns the canonical form of p, resolving . and .. segments and
+// repeated slashes while keeping a trailing slash
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+
+	cleaned := path.Clean(p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// toggleTrailingSlash adds a trailing slash to path or removes it
+func toggleTrailingSlash(path string) string {
+	if strings.HasSuffix(path, "/") {
+		return strings.TrimSuffix(path, "/")
+	}
+	return path + "/"
+}
+
+// allowedMethods returns the methods that can be served for the path segments
+// in tree, including HEAD when it is answered by a GET route
+func (r *Router) allowedMethods(tree *node, segments []string) []string {
+	allowed := tree.allowedMethods(segments)
+	if !r.strictHead && slices.Contains(allowed, http.MethodGet) && !slices.Contains(allowed, http.MethodHead) {
+		allowed = append(allowed, http.MethodHead)
+		slices.Sort(allowed)
+	}
+	return allowed
+}
+
+// serveMethodNotAllowed responds with the custom MethodNotAllowed handler or a
+// default 405 through the router's middleware, setting the Allow header either
+// way
+func (r *Router) serveMethodNotAllowed(w http.ResponseWriter, req *http.Request, allowed []string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	ctx := context.WithValue(req.Context(), allowedMethodsKey, allowed)
+	r.notAllowed.ServeHTTP(w, req.WithContext(ctx))
+}
+
+// serveAutoOptions answers an OPTIONS request with the methods registered for
+// the path, OPTIONS included
+func (r *Router) serveAutoOptions(w http.ResponseWriter, req *http.Request, allowed []string) {
+	allowed = append(allowed, http.MethodOptions)
+	slices.Sort(allowed)
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	ctx := context.WithValue(req.Context(), allowedMethodsKey, allowed)
+	r.autoOptions.ServeHTTP(w, req.WithContext(ctx))
+}
+
+// serveNotFound responds with the custom NotFound handler or a default 404,
+// through the router's middleware either way
+func (r *Router) serveNotFound(w http.ResponseWriter, req *http.Request) {
+	r.notFound.ServeHTTP(w, req)
+}
+
+// headResponseWriter discards the body so a GET handler can answer a HEAD
+// request with the same headers and status
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+// Write reports the body as written without sending it
+func (hw *headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
 }
 
 // URLParam retrieves a URL parameter from the request context
 func URLParam(r *http.Request, key string) string {
-	if value, ok := r.Context().Value(contextKey(key)).(string); ok {
-		return value
+	value, _ := urlParam(r, key)
+	return value
+}
+
+// URLParams returns every URL parameter captured for the request, including
+// those of its host pattern and of enclosing routers when mounted, keyed by
+// name. Where names repeat, the innermost value wins as with URLParam. The map
+// is empty, not nil, when there are no parameters, and belongs to the caller.
+func URLParams(r *http.Request) map[string]string {
+	params, _ := r.Context().Value(paramsKey).([]paramValue)
+	values := make(map[string]string, len(params))
+	for _, param := range params {
+		values[param.key] = param.value
+	}
+	return values
+}
+
+// MatchedRoutePattern returns the template of the route that matched the
+// request, such as /users/{id}, or "" if no route has matched. Unlike the
+// request path it has bounded cardinality, which suits metrics labels.
+func MatchedRoutePattern(r *http.Request) string {
+	return routectx.Pattern(r.Context())
+}
+
+// ConstraintFailure returns the parameter constraint that kept the request from
+// matching a route, when ConstraintDiagnostics is enabled and the router is
+// serving a 404, or nil otherwise
+func ConstraintFailure(r *http.Request) *ConstraintError {
+	failure, _ := r.Context().Value(constraintKey).(*ConstraintError)
+	return failure
+}
+
+// AllowedMethods returns the methods registered for the request path when the
+// router is serving a 405 or automatic OPTIONS response, or nil otherwise
+func AllowedMethods(r *http.Request) []string {
+	if methods, ok := r.Context().Value(allowedMethodsKey).([]string); ok {
+		return methods
 	}
-	return ""
+	return nil
 }
 
 // URLQuery retrieves a query parameter from the URL
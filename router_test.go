@@ -0,0 +1,708 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jtclarkjr/router-go/routertest"
+)
+
+// paramHandler writes the value of the named URL parameter
+func paramHandler(key string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(URLParam(r, key)))
+	}
+}
+
+func TestOptionalParamInGroup(t *testing.T) {
+	r := NewRouter()
+	r.Route("/api", func(api *Router) {
+		api.Route("/v1", func(v1 *Router) {
+			v1.Get("/posts/{id}/{slug?}", paramHandler("slug"))
+		})
+	})
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/api/v1/posts/1", http.StatusOK, ""},
+		{"/api/v1/posts/1/hello", http.StatusOK, "hello"},
+		{"/api/v1/posts", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := routertest.Get(r, tt.path)
+		if rec.Code != tt.code {
+			t.Errorf("GET %s: status = %d, want %d", tt.path, rec.Code, tt.code)
+			continue
+		}
+		if tt.code == http.StatusOK && rec.Body.String() != tt.body {
+			t.Errorf("GET %s: body = %q, want %q", tt.path, rec.Body.String(), tt.body)
+		}
+	}
+
+	if routes := r.Routes(); len(routes) != 1 {
+		t.Errorf("Routes() returned %d routes, want 1", len(routes))
+	}
+}
+
+func TestGroupPrefixParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		colon  bool
+		prefix string
+		route  string
+	}{
+		{"braces", false, "/orgs/{orgID}", "/members/{memberID}"},
+		{"colons", true, "/orgs/:orgID", "/members/:memberID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRouter()
+			r.ColonParams(tt.colon)
+			r.Route(tt.prefix, func(sub *Router) {
+				sub.Get(tt.route, func(w http.ResponseWriter, req *http.Request) {
+					w.Write([]byte(URLParam(req, "orgID") + " " + URLParam(req, "memberID")))
+				})
+			})
+
+			rec := routertest.Get(r, "/orgs/1/members/2")
+			if rec.Code != http.StatusOK || rec.Body.String() != "1 2" {
+				t.Errorf("status = %d, body = %q, want orgID 1 and memberID 2", rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestColonParamsPrefixes(t *testing.T) {
+	r := NewRouter()
+	r.ColonParams(true)
+	r.Route("/api/:version", func(api *Router) {
+		api.Get("/users/:id", func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(URLParam(req, "version") + " " + URLParam(req, "id")))
+		})
+		api.Mount("/orgs/:org", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte(URLParam(req, "org") + " " + req.URL.Path))
+		}))
+	})
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"/api/v1/users/42", "v1 42"},
+		{"/api/v2/orgs/acme/repos", "acme /repos"},
+	}
+	for _, tt := range tests {
+		rec := routertest.Get(r, tt.target)
+		if rec.Code != http.StatusOK || rec.Body.String() != tt.want {
+			t.Errorf("GET %s: status = %d, body = %q, want %q", tt.target, rec.Code, rec.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestColonParamsRejectBracePrefixes(t *testing.T) {
+	register := map[string]func(r *Router){
+		"Route": func(r *Router) { r.Route("/api/{version}", func(*Router) {}) },
+		"Mount": func(r *Router) { r.Mount("/orgs/{org}", http.NotFoundHandler()) },
+	}
+	for name, fn := range register {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("brace prefix accepted with ColonParams")
+				}
+			}()
+			r := NewRouter()
+			r.ColonParams(true)
+			fn(r)
+		})
+	}
+}
+
+// benchPatterns returns n route patterns with two parameters each
+func benchPatterns(n int) []string {
+	patterns := make([]string, n)
+	for i := range patterns {
+		patterns[i] = fmt.Sprintf("/api/resource%d/{id}/items/{item}", i)
+	}
+	return patterns
+}
+
+// BenchmarkLookup500 compares the tree with the linear scan it replaced, in
+// which every request was matched against each route's regexp in turn and
+// its parameters collected into a map. The request matches the last route,
+// the worst case for the scan.
+func BenchmarkLookup500(b *testing.B) {
+	patterns := benchPatterns(500)
+	path := "/api/resource499/42/items/7"
+
+	b.Run("tree", func(b *testing.B) {
+		r := NewRouter()
+		for _, pattern := range patterns {
+			r.Get(pattern, func(http.ResponseWriter, *http.Request) {})
+		}
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, _, ok := r.tree.lookup(http.MethodGet, splitPath(path)); !ok {
+				b.Fatal("no match")
+			}
+		}
+	})
+
+	b.Run("linear", func(b *testing.B) {
+		type linearRoute struct {
+			regex *regexp.Regexp
+			keys  []string
+		}
+		placeholders := strings.NewReplacer("{id}", "([^/]+)", "{item}", "([^/]+)")
+		var routes []linearRoute
+		for _, pattern := range patterns {
+			expr := placeholders.Replace(pattern)
+			routes = append(routes, linearRoute{regexp.MustCompile("^" + expr + "$"), []string{"id", "item"}})
+		}
+		b.ReportAllocs()
+		for b.Loop() {
+			var params map[string]string
+			for _, route := range routes {
+				if match := route.regex.FindStringSubmatch(path); match != nil {
+					params = make(map[string]string, len(route.keys))
+					for i, key := range route.keys {
+						params[key] = match[i+1]
+					}
+					break
+				}
+			}
+			if params == nil {
+				b.Fatal("no match")
+			}
+		}
+	})
+}
+
+// paramRoutes maps request paths to routes with an increasing number of
+// parameters
+var paramRoutes = []struct {
+	name    string
+	pattern string
+	path    string
+}{
+	{"static", "/static", "/static"},
+	{"one", "/one/{a}", "/one/1"},
+	{"three", "/three/{a}/{b}/{c}", "/three/1/2/3"},
+	{"six", "/six/{a}/{b}/{c}/{d}/{e}/{f}", "/six/1/2/3/4/5/6"},
+}
+
+// paramRouter serves paramRoutes with a handler reading parameter a
+func paramRouter() *Router {
+	r := NewRouter()
+	for _, route := range paramRoutes {
+		r.Get(route.pattern, func(w http.ResponseWriter, req *http.Request) {
+			_ = URLParam(req, "a")
+		})
+	}
+	return r
+}
+
+// BenchmarkServeHTTPParams measures a request through ServeHTTP by number of
+// parameters. The parameters share one context value, so allocs/op don't grow
+// with their number.
+func BenchmarkServeHTTPParams(b *testing.B) {
+	r := paramRouter()
+	w := httptest.NewRecorder()
+	for _, route := range paramRoutes {
+		req := httptest.NewRequest(http.MethodGet, route.path, nil)
+		b.Run(route.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				r.ServeHTTP(w, req)
+			}
+		})
+	}
+}
+
+func TestParamAllocsConstant(t *testing.T) {
+	r := paramRouter()
+	w := httptest.NewRecorder()
+	allocs := func(path string) float64 {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		return testing.AllocsPerRun(100, func() { r.ServeHTTP(w, req) })
+	}
+
+	one, six := allocs("/one/1"), allocs("/six/1/2/3/4/5/6")
+	if six > one {
+		t.Errorf("six parameters allocate %v times per request, one parameter %v", six, one)
+	}
+}
+
+func TestParamBacktracking(t *testing.T) {
+	r := NewRouter()
+	r.Get("/files/{dir}/{id:[0-9]+}", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("id " + URLParam(req, "dir") + " " + URLParam(req, "id")))
+	})
+	r.Get("/files/{dir}/{name}/raw", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("raw " + URLParam(req, "dir") + " " + URLParam(req, "name")))
+	})
+	r.Get("/files/{dir}/*", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("rest " + URLParam(req, "dir")))
+	})
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"/files/docs/42", "id docs 42"},
+		{"/files/docs/readme/raw", "raw docs readme"},
+		{"/files/docs/42/raw", "raw docs 42"},
+		{"/files/docs/readme/other", "rest docs"},
+	}
+	for _, tt := range tests {
+		if got := routertest.Get(r, tt.target).Body.String(); got != tt.want {
+			t.Errorf("GET %s: body = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+// nameHandler writes name
+func nameHandler(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name))
+	}
+}
+
+func TestMatchPriority(t *testing.T) {
+	routes := []struct {
+		pattern string
+		name    string
+	}{
+		{"/users/new", "static"},
+		{"/users/{id}", "param"},
+		{"/users/*", "catch-all"},
+		{"/users/{id}/posts", "param static"},
+		{"/users/new/posts", "static static"},
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/users/new", "static"},
+		{"/users/42", "param"},
+		{"/users/42/posts", "param static"},
+		{"/users/new/posts", "static static"},
+		{"/users/42/comments", "catch-all"},
+		{"/users/new/comments", "catch-all"},
+	}
+
+	// Register the routes in every rotation and in reverse, so the same route
+	// must win whatever the order
+	orders := [][]int{}
+	for shift := range routes {
+		order, reversed := []int{}, []int{}
+		for i := range routes {
+			order = append(order, (i+shift)%len(routes))
+			reversed = append(reversed, (len(routes)-1-i+shift)%len(routes))
+		}
+		orders = append(orders, order, reversed)
+	}
+
+	for _, order := range orders {
+		r := NewRouter()
+		for _, i := range order {
+			r.Get(routes[i].pattern, nameHandler(routes[i].name))
+		}
+		for _, tt := range tests {
+			if got := routertest.Get(r, tt.path).Body.String(); got != tt.want {
+				t.Errorf("order %v: GET %s served by %q, want %q", order, tt.path, got, tt.want)
+			}
+		}
+	}
+}
+
+// trace returns a middleware appending name to calls each time it runs
+func trace(name string, calls *[]string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls = append(*calls, name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestGroupMiddlewareRunsOnce(t *testing.T) {
+	var calls []string
+	r := NewRouter()
+	r.Use(trace("parent", &calls))
+	r.Route("/api", func(api *Router) {
+		api.Use(trace("api", &calls))
+		api.Get("/users", func(w http.ResponseWriter, req *http.Request) {
+			calls = append(calls, "handler")
+		})
+		api.With(trace("inline", &calls)).Get("/posts", func(w http.ResponseWriter, req *http.Request) {
+			calls = append(calls, "handler")
+		})
+	})
+	r.Get("/health", func(w http.ResponseWriter, req *http.Request) {
+		calls = append(calls, "handler")
+	})
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/api/users", []string{"parent", "api", "handler"}},
+		{"/api/posts", []string{"parent", "api", "inline", "handler"}},
+		// Group middleware doesn't leak out of the group
+		{"/health", []string{"parent", "handler"}},
+	}
+	for _, tt := range tests {
+		calls = nil
+		routertest.Get(r, tt.path)
+		if !slices.Equal(calls, tt.want) {
+			t.Errorf("GET %s ran %v, want %v", tt.path, calls, tt.want)
+		}
+	}
+}
+
+func TestUseAfterGroupPanics(t *testing.T) {
+	r := NewRouter()
+	r.Route("/api", func(api *Router) {
+		api.Get("/users", func(http.ResponseWriter, *http.Request) {})
+	})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Use after a group's routes were registered didn't panic")
+		}
+	}()
+	r.Use(func(next http.Handler) http.Handler { return next })
+}
+
+func TestNestedGroupsThreeLevels(t *testing.T) {
+	var calls []string
+	r := NewRouter()
+	r.Use(trace("root", &calls))
+	r.Route("/api", func(api *Router) {
+		api.Use(trace("api", &calls))
+		api.Route("/v1", func(v1 *Router) {
+			v1.Use(trace("v1", &calls))
+			v1.Route("/admin", func(admin *Router) {
+				admin.Use(trace("admin", &calls))
+				admin.Get("/users/{id}", func(w http.ResponseWriter, req *http.Request) {
+					calls = append(calls, "handler "+URLParam(req, "id"))
+				})
+			})
+			v1.Get("/status", func(w http.ResponseWriter, req *http.Request) {
+				calls = append(calls, "handler")
+			})
+		})
+	})
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/api/v1/admin/users/7", []string{"root", "api", "v1", "admin", "handler 7"}},
+		{"/api/v1/status", []string{"root", "api", "v1", "handler"}},
+	}
+	for _, tt := range tests {
+		calls = nil
+		rec := routertest.Get(r, tt.path)
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s: status = %d, want 200", tt.path, rec.Code)
+		}
+		if !slices.Equal(calls, tt.want) {
+			t.Errorf("GET %s ran %v, want %v", tt.path, calls, tt.want)
+		}
+	}
+
+	for _, path := range []string{"/admin/users/7", "/v1/admin/users/7", "/api/admin/users/7"} {
+		if rec := routertest.Get(r, path); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s: status = %d, want 404", path, rec.Code)
+		}
+	}
+}
+
+func TestPartialParamMatchIsNotFound(t *testing.T) {
+	r := NewRouter()
+	r.Get("/files/{name}.{ext}", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(URLParam(req, "name") + " " + URLParam(req, "ext")))
+	})
+	r.Get("/items/{id:[0-9]+}", paramHandler("id"))
+	r.Get("/tags/{tag:(go|rust)}-{n}", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(URLParam(req, "tag") + " " + URLParam(req, "n")))
+	})
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/files/readme.md", http.StatusOK, "readme md"},
+		// The segment starts like the template but lacks its literal part
+		{"/files/readme", http.StatusNotFound, ""},
+		{"/files/", http.StatusNotFound, ""},
+		{"/items/12", http.StatusOK, "12"},
+		{"/items/12a", http.StatusNotFound, ""},
+		// A constraint with its own group must not shift the captures
+		{"/tags/go-3", http.StatusOK, "go 3"},
+		{"/tags/java-3", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := routertest.Get(r, tt.path)
+		if rec.Code != tt.code {
+			t.Errorf("GET %s: status = %d, want %d", tt.path, rec.Code, tt.code)
+			continue
+		}
+		if tt.code == http.StatusOK && rec.Body.String() != tt.body {
+			t.Errorf("GET %s: body = %q, want %q", tt.path, rec.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	}
+
+	r := NewRouter()
+	r.Use(trace("requestID", &calls), trace("logger", &calls))
+	r.Get("/plain", handler)
+	r.With(trace("with", &calls)).Get("/with", handler)
+	r.Route("/group", func(g *Router) {
+		g.Use(trace("auth", &calls))
+		g.Get("/route", handler)
+		g.With(trace("with", &calls), trace("with2", &calls)).Get("/with", handler)
+		g.Route("/nested", func(n *Router) {
+			n.Use(trace("nested", &calls))
+			n.With(trace("with", &calls)).Get("/with", handler)
+		})
+	})
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/plain", []string{"requestID", "logger", "handler"}},
+		{"/with", []string{"requestID", "logger", "with", "handler"}},
+		{"/group/route", []string{"requestID", "logger", "auth", "handler"}},
+		{"/group/with", []string{"requestID", "logger", "auth", "with", "with2", "handler"}},
+		{"/group/nested/with", []string{"requestID", "logger", "auth", "nested", "with", "handler"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			calls = nil
+			routertest.Get(r, tt.path)
+			if !slices.Equal(calls, tt.want) {
+				t.Errorf("ran %v, want %v", calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestChainMatchesIndividualUse(t *testing.T) {
+	var calls []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	}
+	a, b, c := trace("a", &calls), trace("b", &calls), trace("c", &calls)
+
+	chained := NewRouter()
+	chained.Use(Chain(a, Chain(b, c)))
+	chained.Get("/", handler)
+
+	individual := NewRouter()
+	individual.Use(a)
+	individual.Use(b)
+	individual.Use(c)
+	individual.Get("/", handler)
+
+	want := []string{"a", "b", "c", "handler"}
+	for name, r := range map[string]*Router{"Chain": chained, "Use": individual} {
+		calls = nil
+		routertest.Get(r, "/")
+		if !slices.Equal(calls, want) {
+			t.Errorf("%s ran %v, want %v", name, calls, want)
+		}
+	}
+
+	// Chain is usable as plain middleware, and copies its arguments
+	mws := []Middleware{a, b}
+	stack := Chain(mws...)
+	mws[0] = c
+	calls = nil
+	stack(http.HandlerFunc(handler)).ServeHTTP(nil, nil)
+	if !slices.Equal(calls, []string{"a", "b", "handler"}) {
+		t.Errorf("Chain ran %v after its arguments changed", calls)
+	}
+}
+
+func TestUseEscapedPath(t *testing.T) {
+	tests := []struct {
+		escaped bool
+		target  string
+		code    int
+		body    string
+	}{
+		{true, "/files/a%2Fb", http.StatusOK, "name a/b"},
+		{true, "/files/report.pdf", http.StatusOK, "name report.pdf"},
+		{true, "/caf%C3%A9", http.StatusOK, "literal"},
+		{true, "/raw/x%2Fy/z", http.StatusOK, "rest x/y/z"},
+		// Matched against the decoded path, the encoded slash splits the segment
+		{false, "/files/a%2Fb", http.StatusNotFound, ""},
+		{false, "/caf%C3%A9", http.StatusOK, "literal"},
+	}
+	for _, tt := range tests {
+		r := NewRouter()
+		r.UseEscapedPath(tt.escaped)
+		r.Get("/files/{name}", func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte("name " + URLParam(req, "name")))
+		})
+		r.Get("/café", nameHandler("literal"))
+		r.Get("/raw/{rest...}", func(w http.ResponseWriter, req *http.Request) {
+			w.Write([]byte("rest " + URLParam(req, "rest")))
+		})
+
+		rec := routertest.Get(r, tt.target)
+		if rec.Code != tt.code {
+			t.Errorf("escaped=%v GET %s: status = %d, want %d", tt.escaped, tt.target, rec.Code, tt.code)
+			continue
+		}
+		if tt.code == http.StatusOK && rec.Body.String() != tt.body {
+			t.Errorf("escaped=%v GET %s: body = %q, want %q", tt.escaped, tt.target, rec.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestNotFoundMiddleware(t *testing.T) {
+	var calls []string
+	custom := func(w http.ResponseWriter, req *http.Request) {
+		calls = append(calls, "notFound")
+		w.WriteHeader(http.StatusNotFound)
+	}
+
+	tests := []struct {
+		name  string
+		setup func(r *Router)
+		want  []string
+	}{
+		{"default", func(r *Router) {
+			r.Use(trace("mw", &calls))
+		}, []string{"mw"}},
+		{"custom", func(r *Router) {
+			r.Use(trace("mw", &calls))
+			r.NotFound(custom)
+		}, []string{"mw", "notFound"}},
+		// Middleware added after NotFound still wraps it
+		{"custom before Use", func(r *Router) {
+			r.Use(trace("a", &calls))
+			r.NotFound(custom)
+			r.Use(trace("b", &calls))
+		}, []string{"a", "b", "notFound"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRouter()
+			tt.setup(r)
+			r.Get("/users", func(http.ResponseWriter, *http.Request) {})
+
+			calls = nil
+			if rec := routertest.Get(r, "/missing"); rec.Code != http.StatusNotFound {
+				t.Errorf("status = %d, want 404", rec.Code)
+			}
+			if !slices.Equal(calls, tt.want) {
+				t.Errorf("ran %v, want %v", calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestMethodNotAllowedMiddleware(t *testing.T) {
+	var calls []string
+	custom := func(w http.ResponseWriter, req *http.Request) {
+		calls = append(calls, "notAllowed")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+
+	tests := []struct {
+		name  string
+		setup func(r *Router)
+		want  []string
+	}{
+		{"default", func(r *Router) {
+			r.Use(trace("mw", &calls))
+		}, []string{"mw"}},
+		{"custom before Use", func(r *Router) {
+			r.MethodNotAllowed(custom)
+			r.Use(trace("mw", &calls))
+		}, []string{"mw", "notAllowed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRouter()
+			tt.setup(r)
+			r.Get("/users/{id}", func(http.ResponseWriter, *http.Request) {})
+
+			calls = nil
+			rec := routertest.Request(r, http.MethodDelete, "/users/5", nil)
+			if rec.Code != http.StatusMethodNotAllowed {
+				t.Errorf("status = %d, want 405", rec.Code)
+			}
+			if got := rec.Header().Get("Allow"); got != "GET, HEAD" {
+				t.Errorf("Allow = %q, want %q", got, "GET, HEAD")
+			}
+			if !slices.Equal(calls, tt.want) {
+				t.Errorf("ran %v, want %v", calls, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutoOptionsMiddleware(t *testing.T) {
+	var calls []string
+	r := NewRouter()
+	r.AutoOptions(true)
+	r.Use(trace("mw", &calls))
+	r.Get("/users", func(http.ResponseWriter, *http.Request) {})
+	r.Post("/users", func(http.ResponseWriter, *http.Request) {})
+
+	rec := routertest.Request(r, http.MethodOptions, "/users", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want 204", rec.Code)
+	}
+	if got := rec.Header().Get("Allow"); got != "GET, HEAD, OPTIONS, POST" {
+		t.Errorf("Allow = %q", got)
+	}
+	if !slices.Equal(calls, []string{"mw"}) {
+		t.Errorf("ran %v, want the middleware added after AutoOptions", calls)
+	}
+
+	r.AutoOptions(false)
+	if rec := routertest.Request(r, http.MethodOptions, "/users", nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("disabled: status = %d, want 405", rec.Code)
+	}
+}
+
+func TestFallbacksInGroupPanic(t *testing.T) {
+	setters := map[string]func(r *Router){
+		"NotFound":         func(r *Router) { r.NotFound(func(http.ResponseWriter, *http.Request) {}) },
+		"MethodNotAllowed": func(r *Router) { r.MethodNotAllowed(func(http.ResponseWriter, *http.Request) {}) },
+		"AutoOptions":      func(r *Router) { r.AutoOptions(true) },
+	}
+	for name, set := range setters {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s inside a group didn't panic", name)
+				}
+			}()
+			NewRouter().Route("/orgs/{orgID}", set)
+		})
+	}
+}
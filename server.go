@@ -0,0 +1,92 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// defaultShutdownTimeout is how long shutdown waits for in-flight requests
+const defaultShutdownTimeout = 10 * time.Second
+
+// Server returns the http.Server used by ListenAndServe, creating it on first
+// use. Configure timeouts or TLS on it before serving:
+//
+//	srv := r.Server()
+//	srv.ReadHeaderTimeout = 5 * time.Second
+//	srv.TLSConfig = tlsConfig
+func (r *Router) Server() *http.Server {
+	if r.server == nil {
+		r.server = &http.Server{Handler: r}
+	}
+	return r.server
+}
+
+// ShutdownTimeout sets how long shutdown waits for in-flight requests to
+// finish before closing their connections. Default value is 10 seconds.
+func (r *Router) ShutdownTimeout(timeout time.Duration) {
+	r.shutdownTimeout = timeout
+}
+
+// ListenAndServe serves the router on addr until the process receives SIGINT
+// or SIGTERM, then shuts down gracefully. It returns nil after a clean
+// shutdown.
+func (r *Router) ListenAndServe(addr string) error {
+	return r.ListenAndServeContext(context.Background(), addr)
+}
+
+// ListenAndServeContext is like ListenAndServe, and also shuts down when ctx
+// is cancelled. The server uses TLS when its TLSConfig has certificates.
+func (r *Router) ListenAndServeContext(ctx context.Context, addr string) error {
+	srv := r.Server()
+	srv.Addr = addr
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 1)
+	go func() {
+		if srv.TLSConfig != nil && (len(srv.TLSConfig.Certificates) > 0 || srv.TLSConfig.GetCertificate != nil) {
+			errs <- srv.ListenAndServeTLS("", "")
+			return
+		}
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		// Shutdown was called directly
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		return r.Shutdown(context.Background())
+	}
+}
+
+// Shutdown stops the server started by ListenAndServe, waiting for in-flight
+// requests up to the shutdown timeout or until ctx is done. If they are still
+// running then, their connections are closed and the context's error is
+// returned.
+func (r *Router) Shutdown(ctx context.Context) error {
+	if r.server == nil {
+		return nil
+	}
+	timeout := r.shutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := r.server.Shutdown(ctx)
+	if ctx.Err() != nil {
+		// Shutdown leaves connections that didn't go idle open, so drop them
+		r.server.Close()
+	}
+	return err
+}
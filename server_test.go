@@ -0,0 +1,135 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// serve starts the router's server on a free local port and returns the
+// base URL
+func serve(t *testing.T, r *Router) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go r.Server().Serve(ln)
+	t.Cleanup(func() { r.Server().Close() })
+	return "http://" + ln.Addr().String()
+}
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	r := NewRouter()
+	r.Get("/slow", func(w http.ResponseWriter, req *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+	url := serve(t, r)
+
+	codes := make(chan int, 1)
+	go func() {
+		resp, err := http.Get(url + "/slow")
+		if err != nil {
+			codes <- 0
+			return
+		}
+		resp.Body.Close()
+		codes <- resp.StatusCode
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- r.Shutdown(context.Background()) }()
+
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Shutdown returned %v before the request finished", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if code := <-codes; code != http.StatusOK {
+		t.Errorf("in-flight request status = %d, want 200", code)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown = %v, want nil", err)
+	}
+}
+
+func TestShutdownTimeoutClosesConnections(t *testing.T) {
+	started := make(chan struct{})
+	r := NewRouter()
+	r.ShutdownTimeout(50 * time.Millisecond)
+	r.Get("/stuck", func(w http.ResponseWriter, req *http.Request) {
+		close(started)
+		<-req.Context().Done()
+	})
+	url := serve(t, r)
+
+	errs := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(url + "/stuck")
+		if err == nil {
+			resp.Body.Close()
+		}
+		errs <- err
+	}()
+	<-started
+
+	if err := r.Shutdown(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown = %v, want context.DeadlineExceeded", err)
+	}
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("stuck request succeeded, want its connection closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stuck request still running after Shutdown returned")
+	}
+}
+
+func TestListenAndServeContext(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	r := NewRouter()
+	r.Get("/", func(w http.ResponseWriter, req *http.Request) {})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.ListenAndServeContext(ctx, addr) }()
+
+	// Wait for the server to accept connections
+	for i := 0; ; i++ {
+		resp, err := http.Get("http://" + addr + "/")
+		if err == nil {
+			resp.Body.Close()
+			break
+		}
+		if i == 100 {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ListenAndServeContext = %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ListenAndServeContext didn't return after cancellation")
+	}
+}
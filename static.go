@@ -0,0 +1,200 @@
+package router
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// StaticConfig defines options for serving static files
+type StaticConfig struct {
+	// ListDirectories enables listings for directories without an index.html.
+	// Listings are disabled by default.
+	ListDirectories bool
+
+	// CacheControl, when set, is sent as the Cache-Control header of every
+	// file served (e.g. "public, max-age=3600")
+	CacheControl string
+
+	// IndexFallback serves the root index.html for paths that don't match a
+	// file, so single-page apps can handle routing on the client
+	IndexFallback bool
+}
+
+// Static serves the files in dir under urlPrefix. Directory listings are
+// disabled, and paths containing ".." segments are rejected.
+func (r *Router) Static(urlPrefix, dir string) {
+	r.StaticWithConfig(urlPrefix, dir, StaticConfig{})
+}
+
+// StaticWithConfig serves the files in dir under urlPrefix with the provided
+// configuration
+func (r *Router) StaticWithConfig(urlPrefix, dir string, config StaticConfig) {
+	r.serveFiles(urlPrefix, http.Dir(dir), config)
+}
+
+// StaticFS serves the files in fsys under urlPrefix, like Static, so an
+// embed.FS can be served without a file system on disk. Use fs.Sub to serve a
+// subdirectory of an embedded tree:
+//
+//	//go:embed dist
+//	var dist embed.FS
+//
+//	site, _ := fs.Sub(dist, "dist")
+//	r.StaticFS("/", site)
+func (r *Router) StaticFS(urlPrefix string, fsys fs.FS) {
+	r.StaticFSWithConfig(urlPrefix, fsys, StaticConfig{})
+}
+
+// StaticFSWithConfig serves the files in fsys under urlPrefix with the
+// provided configuration
+func (r *Router) StaticFSWithConfig(urlPrefix string, fsys fs.FS, config StaticConfig) {
+	r.serveFiles(urlPrefix, http.FS(fsys), config)
+}
+
+// serveFiles registers GET and HEAD routes serving root under urlPrefix
+func (r *Router) serveFiles(urlPrefix string, root http.FileSystem, config StaticConfig) {
+	prefix := strings.TrimSuffix(urlPrefix, "/")
+	handler := stripPrefix(&fileHandler{
+		root:   root,
+		config: config,
+		notFound: func(w http.ResponseWriter, req *http.Request) {
+			// Groups answer with the NotFound handler of the router serving
+			// them. The route's middleware has already run, so skip it here.
+			if notFound := r.root().notFoundFn; notFound != nil {
+				notFound(w, req)
+				return
+			}
+			http.NotFound(w, req)
+		},
+	})
+
+	r.Handle(http.MethodGet, prefix+"/*", handler)
+	r.Handle(http.MethodHead, prefix+"/*", handler)
+}
+
+// fileHandler serves files from a file system, with the URL prefix already
+// stripped from the request path
+type fileHandler struct {
+	root     http.FileSystem
+	config   StaticConfig
+	notFound http.HandlerFunc
+}
+
+// ServeHTTP implements the http.Handler interface
+func (h *fileHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if containsDotDot(req.URL.Path) {
+		http.Error(w, "invalid URL path", http.StatusBadRequest)
+		return
+	}
+
+	name := path.Clean("/" + req.URL.Path)
+	if h.serveFile(w, req, name) {
+		return
+	}
+
+	if h.config.IndexFallback && h.serveFile(w, req, "/index.html") {
+		return
+	}
+	h.notFound(w, req)
+}
+
+// serveFile writes the named file, or the index.html of the named directory,
+// reporting whether anything was served
+func (h *fileHandler) serveFile(w http.ResponseWriter, req *http.Request, name string) bool {
+	f, err := h.root.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	if info.IsDir() {
+		if h.serveFile(w, req, path.Join(name, "index.html")) {
+			return true
+		}
+		if !h.config.ListDirectories {
+			return false
+		}
+		http.FileServer(h.root).ServeHTTP(w, req)
+		return true
+	}
+
+	if h.config.CacheControl != "" {
+		w.Header().Set("Cache-Control", h.config.CacheControl)
+	}
+
+	// ServeContent handles content types, ranges and conditional requests
+	http.ServeContent(w, req, info.Name(), info.ModTime(), f)
+	return true
+}
+
+// containsDotDot reports whether a path has a ".." segment
+func containsDotDot(p string) bool {
+	if !strings.Contains(p, "..") {
+		return false
+	}
+	for _, segment := range strings.FieldsFunc(p, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// SPAConfig defines options for the single-page app fallback
+type SPAConfig struct {
+	// IndexPath is the file served for client-side routes
+	IndexPath string
+
+	// APIPrefix marks paths that always get a real 404, e.g. "/api"
+	APIPrefix string
+
+	// IgnoreAccept serves the index even when the Accept header doesn't
+	// include text/html
+	IgnoreAccept bool
+}
+
+// SPAFallback serves the file at indexPath for unmatched GET and HEAD requests
+// that accept HTML, so client-side routing works on page reloads. Requests for
+// paths with a file extension, such as missing .js or .css assets, still 404.
+func (r *Router) SPAFallback(indexPath string) {
+	r.SPAFallbackWithConfig(SPAConfig{IndexPath: indexPath})
+}
+
+// SPAFallbackWithConfig installs the single-page app fallback with the
+// provided configuration. It becomes the router's NotFound handler, and
+// requests it doesn't serve are passed to the NotFound handler set before it.
+func (r *Router) SPAFallbackWithConfig(config SPAConfig) {
+	previous := r.notFoundFn
+	if previous == nil {
+		previous = http.NotFound
+	}
+
+	r.NotFound(func(w http.ResponseWriter, req *http.Request) {
+		if !servesSPA(req, config) {
+			previous(w, req)
+			return
+		}
+		http.ServeFile(w, req, config.IndexPath)
+	})
+}
+
+// servesSPA reports whether an unmatched request should get the SPA index
+func servesSPA(req *http.Request, config SPAConfig) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	if config.APIPrefix != "" && strings.HasPrefix(req.URL.Path, config.APIPrefix) {
+		return false
+	}
+	if path.Ext(req.URL.Path) != "" {
+		return false
+	}
+	return config.IgnoreAccept || strings.Contains(req.Header.Get("Accept"), "text/html")
+}
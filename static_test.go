@@ -0,0 +1,210 @@
+package router
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jtclarkjr/router-go/routertest"
+)
+
+// staticDir creates a directory of files for Static to serve, with a secret
+// file next to it that must never be reachable
+func staticDir(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"secret.txt":                "secret",
+		"public/app.js":             "console.log(1)",
+		"public/index.html":         "<h1>home</h1>",
+		"public/docs/index.html":    "<h1>docs</h1>",
+		"public/empty/.placeholder": "",
+	}
+	for name, content := range files {
+		file := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(root, "public")
+}
+
+func TestStatic(t *testing.T) {
+	r := NewRouter()
+	r.Static("/assets", staticDir(t))
+
+	tests := []struct {
+		target string
+		code   int
+		body   string
+	}{
+		{"/assets/app.js", http.StatusOK, "console.log(1)"},
+		{"/assets/", http.StatusOK, "<h1>home</h1>"},
+		{"/assets/docs", http.StatusOK, "<h1>docs</h1>"},
+		{"/assets/missing.js", http.StatusNotFound, ""},
+		// Directory listing is disabled by default
+		{"/assets/empty", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := routertest.Get(r, tt.target)
+		if rec.Code != tt.code {
+			t.Errorf("GET %s: status = %d, want %d", tt.target, rec.Code, tt.code)
+			continue
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("GET %s: body = %q, want %q", tt.target, rec.Body.String(), tt.body)
+		}
+	}
+
+	if rec := routertest.Get(r, "/assets/app.js"); rec.Header().Get("Content-Type") != "text/javascript; charset=utf-8" {
+		t.Errorf("Content-Type = %q", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestStaticTraversal(t *testing.T) {
+	r := NewRouter()
+	r.Static("/assets", staticDir(t))
+
+	targets := []string{
+		"/assets/../secret.txt",
+		"/assets/%2e%2e/secret.txt",
+		"/assets/..%2fsecret.txt",
+		"/assets/docs/../../secret.txt",
+		"/assets/..\\secret.txt",
+	}
+	for _, target := range targets {
+		rec := routertest.Get(r, target)
+		if rec.Code == http.StatusOK || rec.Body.String() == "secret" {
+			t.Errorf("GET %s: status = %d, body = %q; traversal not blocked", target, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestStaticConfig(t *testing.T) {
+	r := NewRouter()
+	r.StaticWithConfig("/", staticDir(t), StaticConfig{
+		CacheControl:  "public, max-age=60",
+		IndexFallback: true,
+	})
+
+	rec := routertest.Get(r, "/app.js")
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=60" {
+		t.Errorf("Cache-Control = %q, want %q", got, "public, max-age=60")
+	}
+
+	// Unknown paths fall back to index.html for client-side routing
+	rec = routertest.Get(r, "/users/42")
+	if rec.Code != http.StatusOK || rec.Body.String() != "<h1>home</h1>" {
+		t.Errorf("GET /users/42: status = %d, body = %q, want the index", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStaticInGroup(t *testing.T) {
+	dir := staticDir(t)
+	r := NewRouter()
+	r.Route("/api", func(api *Router) {
+		api.Static("/assets", dir)
+	})
+
+	rec := routertest.Get(r, "/api/assets/app.js")
+	if rec.Code != http.StatusOK || rec.Body.String() != "console.log(1)" {
+		t.Errorf("GET /api/assets/app.js: status = %d, body = %q", rec.Code, rec.Body.String())
+	}
+	if rec := routertest.Get(r, "/api/assets/missing.js"); rec.Code != http.StatusNotFound {
+		t.Errorf("GET /api/assets/missing.js: status = %d, want 404", rec.Code)
+	}
+}
+
+// siteFS is a small embedded-style frontend
+var siteFS = fstest.MapFS{
+	"index.html":       {Data: []byte("<h1>home</h1>")},
+	"app.css":          {Data: []byte("body{}")},
+	"data":             {Data: []byte(`{"a":1}`)},
+	"docs/index.html":  {Data: []byte("<h1>docs</h1>")},
+	"images/logo.svg":  {Data: []byte("<svg/>")},
+	"images/empty.txt": {Data: []byte("")},
+}
+
+func TestStaticFS(t *testing.T) {
+	r := NewRouter()
+	r.NotFound(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("custom not found"))
+	})
+	r.StaticFS("/", siteFS)
+
+	tests := []struct {
+		target      string
+		code        int
+		body        string
+		contentType string
+	}{
+		{"/", http.StatusOK, "<h1>home</h1>", "text/html; charset=utf-8"},
+		{"/app.css", http.StatusOK, "body{}", "text/css; charset=utf-8"},
+		// Without an extension, the type is sniffed from the contents
+		{"/data", http.StatusOK, `{"a":1}`, "text/plain; charset=utf-8"},
+		{"/docs", http.StatusOK, "<h1>docs</h1>", "text/html; charset=utf-8"},
+		{"/docs/", http.StatusOK, "<h1>docs</h1>", "text/html; charset=utf-8"},
+		// Directories without an index aren't listed
+		{"/images", http.StatusNotFound, "custom not found", ""},
+		{"/missing.js", http.StatusNotFound, "custom not found", ""},
+	}
+	for _, tt := range tests {
+		rec := routertest.Get(r, tt.target)
+		if rec.Code != tt.code || rec.Body.String() != tt.body {
+			t.Errorf("GET %s: status = %d, body = %q, want %d, %q", tt.target, rec.Code, rec.Body.String(), tt.code, tt.body)
+		}
+		if tt.contentType != "" && rec.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("GET %s: Content-Type = %q, want %q", tt.target, rec.Header().Get("Content-Type"), tt.contentType)
+		}
+	}
+}
+
+func TestStaticFSPrefixAndFallback(t *testing.T) {
+	r := NewRouter()
+	r.Route("/app", func(app *Router) {
+		app.StaticFSWithConfig("/", siteFS, StaticConfig{IndexFallback: true})
+	})
+
+	tests := []struct {
+		target string
+		code   int
+		body   string
+	}{
+		{"/app/images/logo.svg", http.StatusOK, "<svg/>"},
+		{"/app/settings/profile", http.StatusOK, "<h1>home</h1>"},
+		{"/other", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := routertest.Get(r, tt.target)
+		if rec.Code != tt.code {
+			t.Errorf("GET %s: status = %d, want %d", tt.target, rec.Code, tt.code)
+			continue
+		}
+		if tt.body != "" && rec.Body.String() != tt.body {
+			t.Errorf("GET %s: body = %q, want %q", tt.target, rec.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestStaticNotFoundInGroup(t *testing.T) {
+	r := NewRouter()
+	r.Route("/app", func(app *Router) {
+		app.StaticFS("/", siteFS)
+	})
+	// The handler is looked up when serving, so it can be set after the group
+	r.NotFound(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("custom not found"))
+	})
+
+	rec := routertest.Get(r, "/app/missing.js")
+	if rec.Code != http.StatusNotFound || rec.Body.String() != "custom not found" {
+		t.Errorf("GET /app/missing.js: status = %d, body = %q, want the router's NotFound", rec.Code, rec.Body.String())
+	}
+}
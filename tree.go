@@ -0,0 +1,468 @@
+package router
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// node is a single path segment in the routing tree. Lookup walks one node per
+// request path segment, so its cost depends on the depth of the path rather
+// than the number of registered routes.
+type node struct {
+	// children holds static segments keyed by their literal text
+	children map[string]*node
+
+	// params holds parameterised segments keyed by their raw template text
+	// (e.g. "{id}" or "{name}.json"), kept in the order they are tried
+	params []*node
+
+	// catchAll matches all remaining segments of the request path
+	catchAll *node
+
+	// segment is the raw template text of this node
+	segment string
+
+	// paramKeys are the parameter names captured by this segment
+	paramKeys []string
+
+	// paramPattern is set when the segment is more than a single bare
+	// parameter and needs a regular expression to match
+	paramPattern *regexp.Regexp
+
+	// paramGroups are the submatch indexes of paramKeys in paramPattern
+	paramGroups []int
+
+	// pattern is the full path template of the routes stored at this node
+	pattern string
+
+	// routes maps methods to the routes registered at this node
+	routes map[string]Route
+}
+
+// paramValue is a captured URL parameter
+type paramValue struct {
+	key   string
+	value string
+}
+
+// newNode creates an empty tree node
+func newNode() *node {
+	return &node{children: make(map[string]*node)}
+}
+
+// splitPath splits a path into its segments, ignoring the leading slash
+func splitPath(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
+
+// insert adds a route for the given method under the path template. It
+// panics if the method is already registered for the template, or for one
+// that only differs in parameter names and so matches the same requests. A
+// trailing optional parameter stores the route both with and without its
+// segment.
+func (n *node) insert(method, path string, route Route) {
+	segments := splitPath(path)
+	if params := parseParams(segments[len(segments)-1]); len(params) == 1 && params[0].optional {
+		absent := segments[:len(segments)-1]
+		if len(absent) == 0 {
+			absent = []string{""}
+		}
+		n.insertSegments(method, path, absent, route)
+	}
+	n.insertSegments(method, path, segments, route)
+}
+
+// insertSegments stores a route for the template path under the given
+// template segments
+func (n *node) insertSegments(method, path string, segments []string, route Route) {
+	if existing := n.find(method, segments); existing != "" && existing != path {
+		panic(fmt.Sprintf("router: %s %s conflicts with %s %s", method, path, method, existing))
+	}
+
+	current := n
+	for i, segment := range segments {
+		current = current.child(segment, i == len(segments)-1)
+	}
+
+	if current.routes == nil {
+		current.routes = make(map[string]Route)
+	}
+	current.pattern = path
+
+	// Explicit routes replace Any routes, never the other way around
+	if existing, ok := current.routes[method]; ok {
+		switch {
+		case route.anyMethod && !existing.anyMethod:
+			return
+		case route.anyMethod == existing.anyMethod:
+			panic(fmt.Sprintf("router: %s %s is already registered", method, path))
+		}
+	}
+	current.routes[method] = route
+}
+
+// find returns the pattern of the route registered for method whose template
+// has the same shape as the remaining segments, ignoring parameter names
+func (n *node) find(method string, segments []string) string {
+	if len(segments) == 0 {
+		if route, ok := n.routes[method]; ok {
+			return route.Pattern
+		}
+		return ""
+	}
+
+	segment, rest := segments[0], segments[1:]
+	params := parseParams(segment)
+	whole := len(params) == 1 && params[0].start == 0 && params[0].end == len(segment)
+
+	if len(rest) == 0 && (segment == "*" || whole && params[0].catchAll) {
+		if n.catchAll == nil {
+			return ""
+		}
+		return n.catchAll.find(method, rest)
+	}
+
+	if len(params) == 0 {
+		if child, ok := n.children[segment]; ok {
+			return child.find(method, rest)
+		}
+		return ""
+	}
+
+	shape := segmentShape(segment, params)
+	for _, child := range n.params {
+		if segmentShape(child.segment, parseParams(child.segment)) != shape {
+			continue
+		}
+		if pattern := child.find(method, rest); pattern != "" {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// segmentShape strips the parameter names from a template segment, so
+// segments matching exactly the same request segments compare equal
+func segmentShape(segment string, params []pathParam) string {
+	var b strings.Builder
+	last := 0
+	for _, param := range params {
+		b.WriteString(segment[last:param.start])
+		switch {
+		case param.catchAll:
+			b.WriteString("{...}")
+		case param.constraint != "":
+			b.WriteString("{:" + param.constraint + "}")
+		default:
+			b.WriteString("{}")
+		}
+		last = param.end
+	}
+	b.WriteString(segment[last:])
+	return b.String()
+}
+
+// child returns the child node for a template segment, creating it if needed
+func (n *node) child(segment string, last bool) *node {
+	params := parseParams(segment)
+	whole := len(params) == 1 && params[0].start == 0 && params[0].end == len(segment)
+
+	// A trailing "*" or "{name...}" matches everything below this point
+	if last && (segment == "*" || whole && params[0].catchAll) {
+		// "*" and "{name...}" differ in what they capture, so they can't
+		// share the catch-all node, whatever the method
+		if n.catchAll != nil && n.catchAll.segment != segment {
+			panic(fmt.Sprintf("router: catch-all %s conflicts with %s", segment, n.catchAll.pattern))
+		}
+		if n.catchAll == nil {
+			n.catchAll = newNode()
+			n.catchAll.segment = segment
+			if segment != "*" {
+				n.catchAll.paramKeys = []string{params[0].name}
+			}
+		}
+		return n.catchAll
+	}
+
+	if len(params) == 0 {
+		if child, ok := n.children[segment]; ok {
+			return child
+		}
+		child := newNode()
+		child.segment = segment
+		n.children[segment] = child
+		return child
+	}
+
+	for _, child := range n.params {
+		if child.segment == segment {
+			return child
+		}
+	}
+
+	child := newNode()
+	child.segment = segment
+	for _, param := range params {
+		child.paramKeys = append(child.paramKeys, param.name)
+	}
+
+	// Constrained parameters, and segments that mix parameters with literal
+	// text, need a regex to match
+	if !whole || params[0].constraint != "" {
+		child.paramPattern = compilePattern(segment, params, `.+?`)
+		child.paramGroups = groupIndexes(child.paramPattern, len(params))
+	}
+	n.params = append(n.params, child)
+	slices.SortFunc(n.params, compareParams)
+	return child
+}
+
+// compareParams orders parameter segments so matching doesn't depend on
+// registration order: segments needing a regex (constraints or literal text)
+// are more specific than a bare {param}, and ties are broken by template text
+func compareParams(a, b *node) int {
+	if (a.paramPattern == nil) != (b.paramPattern == nil) {
+		if a.paramPattern != nil {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a.segment, b.segment)
+}
+
+// lookup finds the route registered for method that matches the request path
+// segments, returning the captured parameters along with it
+func (n *node) lookup(method string, segments []string) (Route, []paramValue, bool) {
+	leaf, params := n.match(method, segments, nil)
+	if leaf == nil {
+		return Route{}, nil, false
+	}
+	return leaf.routes[method], params, true
+}
+
+// match walks the tree for the remaining segments, preferring static segments
+// over parameters and parameters over catch-alls, and backtracking when a
+// branch does not lead to a route for method
+func (n *node) match(method string, segments []string, params []paramValue) (*node, []paramValue) {
+	if len(segments) == 0 {
+		if _, ok := n.routes[method]; ok {
+			return n, params
+		}
+	} else {
+		segment, rest := segments[0], segments[1:]
+
+		if child, ok := n.children[segment]; ok {
+			if leaf, found := child.match(method, rest, params); leaf != nil {
+				return leaf, found
+			}
+		}
+
+		if segment != "" && len(n.params) > 0 {
+			// Size the parameters for the rest of the path up front, so they
+			// are allocated once however many there are. Branches that fail
+			// to match only leave values past the end of params.
+			if params == nil {
+				params = make([]paramValue, 0, len(segments))
+			}
+			for _, child := range n.params {
+				captured, ok := child.capture(segment, params)
+				if !ok {
+					continue
+				}
+				if leaf, found := child.match(method, rest, captured); leaf != nil {
+					return leaf, found
+				}
+			}
+		}
+	}
+
+	// Catch-alls only match once nothing more specific does
+	if n.catchAll != nil {
+		if _, ok := n.catchAll.routes[method]; ok {
+			if captured, ok := n.catchAll.captureRest(segments, params); ok {
+				return n.catchAll, captured
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// capture matches a request segment against a parameter node and appends the
+// captured values to params
+func (n *node) capture(segment string, params []paramValue) ([]paramValue, bool) {
+	if n.paramPattern == nil {
+		return append(params, paramValue{key: n.paramKeys[0], value: segment}), true
+	}
+
+	// Treat anything short of a full set of submatches as a non-match rather
+	// than indexing past the end
+	matches := n.paramPattern.FindStringSubmatch(segment)
+	if matches == nil || len(matches) <= len(n.paramKeys) {
+		return nil, false
+	}
+	for i, key := range n.paramKeys {
+		params = append(params, paramValue{key: key, value: matches[n.paramGroups[i]]})
+	}
+	return params, true
+}
+
+// allowedMethods returns the sorted methods of every route matching the
+// request path segments, regardless of the request method
+func (n *node) allowedMethods(segments []string) []string {
+	found := make(map[string]bool)
+	n.collectMethods(segments, found)
+
+	methods := make([]string, 0, len(found))
+	for method := range found {
+		methods = append(methods, method)
+	}
+	slices.Sort(methods)
+	return methods
+}
+
+// collectMethods adds the methods of every route matching the remaining
+// segments to found
+func (n *node) collectMethods(segments []string, found map[string]bool) {
+	if n.catchAll != nil {
+		if _, ok := n.catchAll.captureRest(segments, nil); ok {
+			for method := range n.catchAll.routes {
+				found[method] = true
+			}
+		}
+	}
+
+	if len(segments) == 0 {
+		for method := range n.routes {
+			found[method] = true
+		}
+		return
+	}
+
+	segment, rest := segments[0], segments[1:]
+
+	if child, ok := n.children[segment]; ok {
+		child.collectMethods(rest, found)
+	}
+
+	if segment != "" {
+		for _, child := range n.params {
+			if _, ok := child.capture(segment, nil); ok {
+				child.collectMethods(rest, found)
+			}
+		}
+	}
+}
+
+// constraintFailure looks for a route for method that the segments would
+// match if a single parameter constraint were ignored, returning the route's
+// pattern and the rejected parameter. Only parameters that make up a whole
+// segment, like {id:int}, are considered.
+func (n *node) constraintFailure(method string, segments []string, failure *ConstraintError) (string, *ConstraintError) {
+	if failure != nil && n.catchAll != nil {
+		if _, ok := n.catchAll.routes[method]; ok {
+			if _, ok := n.catchAll.captureRest(segments, nil); ok {
+				return n.catchAll.pattern, failure
+			}
+		}
+	}
+
+	if len(segments) == 0 {
+		if _, ok := n.routes[method]; ok && failure != nil {
+			return n.pattern, failure
+		}
+		return "", nil
+	}
+
+	segment, rest := segments[0], segments[1:]
+
+	if child, ok := n.children[segment]; ok {
+		if pattern, found := child.constraintFailure(method, rest, failure); found != nil {
+			return pattern, found
+		}
+	}
+
+	if segment == "" {
+		return "", nil
+	}
+	for _, child := range n.params {
+		next := failure
+		if _, ok := child.capture(segment, nil); !ok {
+			if failure != nil {
+				continue
+			}
+			if next = child.rejected(segment); next == nil {
+				continue
+			}
+		}
+		if pattern, found := child.constraintFailure(method, rest, next); found != nil {
+			return pattern, found
+		}
+	}
+	return "", nil
+}
+
+// rejected describes the constraint a request segment failed, if the node is
+// a single constrained parameter making up the whole segment. The constraint
+// is the expanded one stored in the tree.
+func (n *node) rejected(segment string) *ConstraintError {
+	params := parseParams(n.segment)
+	if len(params) != 1 || params[0].start != 0 || params[0].end != len(n.segment) || params[0].constraint == "" {
+		return nil
+	}
+	return &ConstraintError{Param: params[0].name, Constraint: params[0].constraint, Value: segment}
+}
+
+// captureRest matches the remaining request segments against a catch-all
+// node. A bare "*" also matches nothing, while a named catch-all needs at least
+// one character to capture.
+func (n *node) captureRest(segments []string, params []paramValue) ([]paramValue, bool) {
+	if len(n.paramKeys) == 0 {
+		return params, true
+	}
+
+	rest := strings.Join(segments, "/")
+	if rest == "" {
+		return nil, false
+	}
+	return append(params, paramValue{key: n.paramKeys[0], value: rest}), true
+}
+
+// empty reports whether no routes have been inserted under the node. Nodes
+// are only created by insert, so any child means a route exists.
+func (n *node) empty() bool {
+	return len(n.routes) == 0 && len(n.children) == 0 && len(n.params) == 0 && n.catchAll == nil
+}
+
+// walk calls fn once for every route stored in the tree. A route with an
+// optional segment is stored at two nodes but reported once, so routes can be
+// re-added elsewhere, as Route does, without being registered twice.
+func (n *node) walk(fn func(pattern, method string, route Route)) {
+	seen := make(map[[2]string]bool)
+	n.walkNodes(func(pattern, method string, route Route) {
+		key := [2]string{pattern, method}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		fn(pattern, method, route)
+	})
+}
+
+// walkNodes calls fn for the routes of every node in the tree
+func (n *node) walkNodes(fn func(pattern, method string, route Route)) {
+	for method, route := range n.routes {
+		fn(n.pattern, method, route)
+	}
+	for _, child := range n.children {
+		child.walkNodes(fn)
+	}
+	for _, child := range n.params {
+		child.walkNodes(fn)
+	}
+	if n.catchAll != nil {
+		n.catchAll.walkNodes(fn)
+	}
+}
@@ -0,0 +1,78 @@
+package router
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// URLFor builds the path of a named route from alternating parameter names and
+// values. It returns an error if the name is unknown, a parameter is missing
+// or not part of the route, or a value fails the parameter's constraint.
+//
+//	r.URLFor("user.show", "id", "42") // "/users/42"
+func (r *Router) URLFor(name string, pairs ...string) (string, error) {
+	pattern, ok := r.names[name]
+	if !ok {
+		return "", fmt.Errorf("router: no route named %q", name)
+	}
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("router: URLFor(%q) needs parameter name/value pairs", name)
+	}
+	if strings.HasSuffix(pattern, "/*") || pattern == "*" {
+		return "", fmt.Errorf("router: route %q ends in an unnamed wildcard and cannot be built", name)
+	}
+
+	values := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		values[pairs[i]] = pairs[i+1]
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, param := range parseParams(pattern) {
+		value, ok := values[param.name]
+		if !ok && param.optional {
+			// Leave out the optional segment along with its slash
+			prefix := strings.TrimSuffix(b.String()+pattern[last:param.start], "/")
+			if prefix == "" {
+				prefix = "/"
+			}
+			b.Reset()
+			b.WriteString(prefix)
+			last = param.end
+			continue
+		}
+		if !ok {
+			return "", fmt.Errorf("router: route %q is missing parameter %q", name, param.name)
+		}
+		delete(values, param.name)
+
+		if param.constraint != "" && !anchoredConstraint(param.constraint).MatchString(value) {
+			return "", fmt.Errorf("router: value %q for parameter %q of route %q does not match %s", value, param.name, name, param.constraint)
+		}
+
+		b.WriteString(pattern[last:param.start])
+		if param.catchAll {
+			b.WriteString(escapeSegments(value))
+		} else {
+			b.WriteString(url.PathEscape(value))
+		}
+		last = param.end
+	}
+	b.WriteString(pattern[last:])
+
+	for key := range values {
+		return "", fmt.Errorf("router: route %q has no parameter %q", name, key)
+	}
+	return b.String(), nil
+}
+
+// escapeSegments escapes each segment of a slash-separated path value
+func escapeSegments(value string) string {
+	segments := strings.Split(value, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
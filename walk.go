@@ -0,0 +1,85 @@
+package router
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+)
+
+// RouteInfo describes a registered route
+type RouteInfo struct {
+	Method  string
+	Pattern string
+
+	// Host is the host pattern of routes registered with Host, or ""
+	Host string
+
+	// Middlewares is the number of middleware wrapping the route's handler
+	Middlewares int
+}
+
+// WalkFunc is called by Walk for every registered route. handler is the
+// route's handler as served, already wrapped with middlewares. The pattern of
+// a route registered with Host is prefixed with its host, as in
+// "api.example.com/users".
+type WalkFunc func(method, pattern string, handler http.Handler, middlewares ...Middleware) error
+
+// Walk calls fn for every registered route, ordered by host, pattern and then
+// method, including the routes of groups and hosts and those added by Mount
+// and Static.
+// It stops at the first error fn returns and returns it.
+func (r *Router) Walk(fn WalkFunc) error {
+	for _, entry := range r.sortedRoutes() {
+		if err := fn(entry.method, entry.host+entry.route.Pattern, entry.route.Handler, entry.route.middlewares...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Routes returns a snapshot of the registered routes, ordered like Walk
+func (r *Router) Routes() []RouteInfo {
+	entries := r.sortedRoutes()
+	routes := make([]RouteInfo, 0, len(entries))
+	for _, entry := range entries {
+		routes = append(routes, RouteInfo{
+			Method:      entry.method,
+			Pattern:     entry.route.Pattern,
+			Host:        entry.host,
+			Middlewares: len(entry.route.middlewares),
+		})
+	}
+	return routes
+}
+
+// routeEntry is a route and the method and host it is registered for
+type routeEntry struct {
+	host   string
+	method string
+	route  Route
+}
+
+// sortedRoutes collects the routes of the tree in a stable order
+func (r *Router) sortedRoutes() []routeEntry {
+	var entries []routeEntry
+	collect := func(host string, tree *node) {
+		tree.walk(func(pattern, method string, route Route) {
+			entries = append(entries, routeEntry{host: host, method: method, route: route})
+		})
+	}
+	collect("", r.tree)
+	if r.hosts != nil {
+		for _, host := range r.hosts.hosts {
+			collect(host.pattern, host.tree)
+		}
+	}
+
+	slices.SortFunc(entries, func(a, b routeEntry) int {
+		return cmp.Or(
+			cmp.Compare(a.host, b.host),
+			cmp.Compare(a.route.Pattern, b.route.Pattern),
+			cmp.Compare(a.method, b.method),
+		)
+	})
+	return entries
+}